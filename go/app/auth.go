@@ -0,0 +1,246 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// sessionTTL is how long a session token is valid after login.
+	sessionTTL = 30 * 24 * time.Hour
+	// minPasswordLength is the minimum length of a password in bytes.
+	minPasswordLength = 8
+	// maxPasswordLength is the maximum length of a password in bytes, the most bcrypt hashes.
+	maxPasswordLength = 72
+)
+
+// userNamePattern matches the names users can sign up with.
+var userNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,32}$`)
+
+// userKey is the context key of the authenticated user.
+type userKey struct{}
+
+// userFromContext returns the authenticated user of the request, if any.
+func userFromContext(ctx context.Context) (*User, bool) {
+	user, ok := ctx.Value(userKey{}).(*User)
+	return user, ok
+}
+
+// hashPassword returns the bcrypt hash of password.
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// dummyPasswordHash is compared against when a user does not exist,
+// so that logging in as an unknown user takes as long as with a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	return hash
+})
+
+// newSessionToken returns a random opaque session token.
+func newSessionToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hash of a session token stored in the database.
+// Tokens are random, so a fast hash is enough.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// bearerToken returns the token in the Authorization header, if any.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// authMiddleware puts the user of the session token in the Authorization header into the request context.
+// Requests without a valid token are passed on without a user, and handlers that need one respond 401.
+func authMiddleware(next http.Handler, users UserRepository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		user, err := users.GetSessionUser(r.Context(), hashToken(token), time.Now())
+		if err != nil {
+			if !errors.Is(err, errSessionNotFound) {
+				slog.ErrorContext(r.Context(), "failed to get session: ", "error", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userKey{}, user)))
+	})
+}
+
+// currentUser returns the authenticated user of the request.
+// It writes a 401 response and returns nil if the request has no valid session token.
+func currentUser(w http.ResponseWriter, r *http.Request) *User {
+	user, ok := userFromContext(r.Context())
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSONError(w, http.StatusUnauthorized, "login required")
+		return nil
+	}
+	return user
+}
+
+type AuthRequest struct {
+	Name     string `form:"name"`
+	Password string `form:"password"`
+}
+
+// AuthResponse is returned on signup and login.
+// Token is sent as "Authorization: Bearer <token>" in later requests.
+type AuthResponse struct {
+	User      *User     `json:"user"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// parseAuthRequest parses the request to sign up or log in.
+func parseAuthRequest(r *http.Request) *AuthRequest {
+	return &AuthRequest{
+		Name:     strings.TrimSpace(r.FormValue("name")),
+		Password: r.FormValue("password"),
+	}
+}
+
+// validateSignupRequest validates the name and password of a new user.
+func validateSignupRequest(req *AuthRequest) error {
+	if !userNamePattern.MatchString(req.Name) {
+		return errors.New("name must be 3 to 32 letters, digits, '_', '.' or '-'")
+	}
+	if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+		return fmt.Errorf("password must be %d to %d bytes", minPasswordLength, maxPasswordLength)
+	}
+	return nil
+}
+
+// Signup is a handler to create a user for POST /auth/signup .
+// The user is logged in, and a session token is returned.
+func (s *Handlers) Signup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := parseAuthRequest(r)
+	if err := validateSignupRequest(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	user, err := s.userRepo.CreateUser(ctx, req.Name, hash)
+	if err != nil {
+		if errors.Is(err, errUserExists) {
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("user %s already exists", req.Name))
+			return
+		}
+		slog.ErrorContext(ctx, "failed to create user: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(ctx, "user created", "id", user.ID)
+
+	s.startSession(w, r, user, http.StatusCreated)
+}
+
+// Login is a handler to log in for POST /auth/login .
+// It returns a new session token.
+func (s *Handlers) Login(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := parseAuthRequest(r)
+	user, err := s.userRepo.GetUserByName(ctx, req.Name)
+	if err != nil && !errors.Is(err, errUserNotFound) {
+		slog.ErrorContext(ctx, "failed to get user: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	hash := dummyPasswordHash()
+	if user != nil {
+		hash = []byte(user.PasswordHash)
+	}
+	// the password is checked even for unknown users, so that response times do not reveal which names exist
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || user == nil {
+		writeJSONError(w, http.StatusUnauthorized, "invalid name or password")
+		return
+	}
+
+	s.startSession(w, r, user, http.StatusOK)
+}
+
+// startSession creates a session of user and writes its token with the status code.
+func (s *Handlers) startSession(w http.ResponseWriter, r *http.Request, user *User, code int) {
+	ctx := r.Context()
+
+	token, err := newSessionToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	expiresAt := time.Now().Add(sessionTTL).Truncate(time.Second)
+	if err := s.userRepo.CreateSession(ctx, hashToken(token), user.ID, expiresAt); err != nil {
+		slog.ErrorContext(ctx, "failed to create session: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(ctx, "user logged in", "id", user.ID)
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, code, AuthResponse{User: user, Token: token, ExpiresAt: expiresAt})
+}
+
+// Logout is a handler to log out for POST /auth/logout .
+// The session token of the request can no longer be used.
+func (s *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := currentUser(w, r)
+	if user == nil {
+		return
+	}
+	// the token is valid, since the auth middleware found its user
+	token, _ := bearerToken(r)
+	if err := s.userRepo.DeleteSession(ctx, hashToken(token)); err != nil {
+		slog.ErrorContext(ctx, "failed to delete session: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(ctx, "user logged out", "id", user.ID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,87 @@
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	// immutableCacheControl is sent for content-addressed images.
+	// Their names change whenever their content does, so they can be cached for a year without revalidation.
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// fallbackCacheControl is sent for the default image and for images served in place of a missing one.
+	// The requested image may be uploaded later, so the fallback is only cached briefly.
+	fallbackCacheControl = "public, max-age=300"
+	// revalidateCacheControl is sent for API responses with an ETag.
+	// Clients may store them but must revalidate them before every use.
+	revalidateCacheControl = "no-cache"
+)
+
+// hashedImageName matches the names of content-addressed images and their resized variants,
+// e.g. <sha256>.jpg and <sha256>_w400.jpg.
+var hashedImageName = regexp.MustCompile(`^[0-9a-f]{64}(_w[0-9]+)?\.[a-z]+$`)
+
+// setImageCacheHeaders sets the caching headers of the image name.
+// fallback reports whether the image is served in place of the requested one,
+// which must not be cached as if it were the requested image.
+// Content-addressed images get their name as the ETag, since it identifies their content.
+func setImageCacheHeaders(h http.Header, name string, fallback bool) {
+	if fallback || !hashedImageName.MatchString(name) {
+		h.Set("Cache-Control", fallbackCacheControl)
+		return
+	}
+	h.Set("Cache-Control", immutableCacheControl)
+	h.Set("ETag", `"`+name+`"`)
+}
+
+// contentETag returns a strong ETag derived from the content of a response body.
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether the If-None-Match header matches etag.
+// It uses the weak comparison, as required for If-None-Match.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSONWithETag writes resp as a 200 JSON response with an ETag of its content.
+// If the request already has the same content, as told by If-None-Match,
+// it writes 304 Not Modified without a body instead.
+func writeJSONWithETag(w http.ResponseWriter, r *http.Request, resp any) {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(resp); err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode response: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	etag := contentETag(buf.Bytes())
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", revalidateCacheControl)
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		slog.WarnContext(r.Context(), "failed to write response: ", "error", err)
+	}
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestEtagMatches(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		"ok: same":              {ifNoneMatch: `"abc"`, want: true},
+		"ok: in a list":         {ifNoneMatch: `"def", "abc"`, want: true},
+		"ok: weak":              {ifNoneMatch: `W/"abc"`, want: true},
+		"ok: any":               {ifNoneMatch: `*`, want: true},
+		"ng: empty":             {ifNoneMatch: ``},
+		"ng: different":         {ifNoneMatch: `"def"`},
+		"ng: unquoted":          {ifNoneMatch: `abc`},
+		"ng: different in list": {ifNoneMatch: `"def", "ghi"`},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := etagMatches(tt.ifNoneMatch, `"abc"`); got != tt.want {
+				t.Errorf("etagMatches(%q) = %v, want %v", tt.ifNoneMatch, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetImageCacheHeaders(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryImageStore()
+	h := &Handlers{imgStore: store}
+	ctx := context.Background()
+	filePath := storeTestImage(t, h, newSizedTestImage(t, "image/png", 800, 600))
+	name := path.Base(filePath)
+	defaultImage := readTestImage(t, "../images/default.jpg")
+	if err := store.Put(ctx, defaultImageName, bytes.NewReader(defaultImage), int64(len(defaultImage)), "image/jpeg"); err != nil {
+		t.Fatalf("failed to put default image: %v", err)
+	}
+
+	type wants struct {
+		code         int
+		cacheControl string
+		etag         string
+	}
+	cases := map[string]struct {
+		path        string
+		ifNoneMatch string
+		wants
+	}{
+		"ok: hashed image is immutable": {
+			path:  "/images/" + name,
+			wants: wants{code: http.StatusOK, cacheControl: immutableCacheControl, etag: `"` + name + `"`},
+		},
+		"ok: hashed variant is immutable": {
+			path:  "/images/" + name + "?w=400",
+			wants: wants{code: http.StatusOK, cacheControl: immutableCacheControl, etag: `"` + strings.TrimSuffix(name, ".png") + `_w400.png"`},
+		},
+		"ok: not modified": {
+			path:        "/images/" + name,
+			ifNoneMatch: `"` + name + `"`,
+			wants:       wants{code: http.StatusNotModified, cacheControl: immutableCacheControl, etag: `"` + name + `"`},
+		},
+		"ok: default image for a missing image is cached briefly": {
+			path:  "/images/" + strings.Repeat("0", 64) + ".jpg",
+			wants: wants{code: http.StatusOK, cacheControl: fallbackCacheControl},
+		},
+		"ok: default image is cached briefly": {
+			path:  "/images/default.jpg",
+			wants: wants{code: http.StatusOK, cacheControl: fallbackCacheControl},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /images/{filename}", h.GetImage)
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, req)
+
+			if res.Code != tt.code {
+				t.Errorf("expected status code %d, got %d", tt.code, res.Code)
+			}
+			if got := res.Header().Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("expected Cache-Control %q, got %q", tt.cacheControl, got)
+			}
+			if got := res.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("expected ETag %q, got %q", tt.etag, got)
+			}
+		})
+	}
+}
+
+func TestGetItemByIDNotModified(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockIR := NewMockItemRepository(ctrl)
+	item := &Item{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg"}
+	mockIR.EXPECT().GetByID(gomock.Any(), 1).Return(item, nil).Times(3)
+	h := &Handlers{itemRepo: mockIR}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", h.GetItemByID)
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/items/1", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		res := httptest.NewRecorder()
+		mux.ServeHTTP(res, req)
+		return res
+	}
+
+	res := get("")
+	etag := res.Header().Get("ETag")
+	if res.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an ETag, got %d and %q", res.Code, etag)
+	}
+	if got := res.Header().Get("Cache-Control"); got != revalidateCacheControl {
+		t.Errorf("expected Cache-Control %q, got %q", revalidateCacheControl, got)
+	}
+
+	// the same item is not sent again
+	res = get(etag)
+	if res.Code != http.StatusNotModified || res.Body.Len() != 0 {
+		t.Errorf("expected 304 without a body, got %d with %q", res.Code, res.Body.String())
+	}
+
+	// a changed item is sent
+	item.Name = "denim jacket"
+	res = get(etag)
+	if res.Code != http.StatusOK || res.Header().Get("ETag") == etag {
+		t.Errorf("expected 200 with a new ETag, got %d and %q", res.Code, res.Header().Get("ETag"))
+	}
+}
+
+func TestGetItemsNotModified(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockIR := NewMockItemRepository(ctrl)
+	items := []Item{{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg"}}
+	mockIR.EXPECT().ListItems(gomock.Any(), gomock.Any()).Return(items, false, nil).Times(2)
+	h := &Handlers{itemRepo: mockIR}
+
+	res := httptest.NewRecorder()
+	h.GetItem(res, httptest.NewRequest("GET", "/items", nil))
+	etag := res.Header().Get("ETag")
+	if res.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with an ETag, got %d and %q", res.Code, etag)
+	}
+
+	req := httptest.NewRequest("GET", "/items", nil)
+	req.Header.Set("If-None-Match", etag)
+	res = httptest.NewRecorder()
+	h.GetItem(res, req)
+	if res.Code != http.StatusNotModified {
+		t.Errorf("expected status code %d, got %d", http.StatusNotModified, res.Code)
+	}
+}
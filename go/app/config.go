@@ -0,0 +1,420 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This file builds a Server from its configuration sources.
+// Each setting is taken from the first of the following that specifies it:
+//
+//  1. command-line flags (e.g. -port 9001)
+//  2. MERCARI_* environment variables (e.g. MERCARI_PORT=9001)
+//  3. the JSON config file given by -config or MERCARI_CONFIG (e.g. {"port": "9001"})
+//  4. the defaults below
+//
+// FRONT_URL is still read as the allowed origin when no other source sets one.
+
+const (
+	defaultPort           = "9001"
+	defaultDBPath         = "db/mercari.sqlite3"
+	defaultImageDirPath   = "images"
+	defaultAllowedOrigin  = "http://localhost:3000"
+	defaultLogLevel       = "debug"
+	defaultLogFormat      = "json"
+	defaultMaxUploadBytes = 10 << 20
+	defaultCORSMaxAge     = "10m"
+	defaultExposedHeaders = "X-Request-ID"
+	defaultImageStore     = "local"
+	defaultGCInterval     = "1h"
+	defaultGCGracePeriod  = "24h"
+)
+
+// minGCGracePeriod is the shortest image gc grace period. An image is uploaded before the item
+// that uses it is stored, so a shorter one could delete images of items that are being created.
+const minGCGracePeriod = 5 * time.Minute
+
+// fileConfig is the content of the config file.
+// Fields that are not specified are nil.
+type fileConfig struct {
+	Port             *string  `json:"port"`
+	DBPath           *string  `json:"db_path"`
+	ImageDir         *string  `json:"image_dir"`
+	AllowedOrigins   []string `json:"allowed_origins"`
+	LogLevel         *string  `json:"log_level"`
+	LogFormat        *string  `json:"log_format"`
+	MaxUploadBytes   *int64   `json:"max_upload_bytes"`
+	AdminToken       *string  `json:"admin_token"`
+	StrictCategories *bool    `json:"strict_categories"`
+	CORSMaxAge       *string  `json:"cors_max_age"`
+	CORSCredentials  *bool    `json:"cors_allow_credentials"`
+	CORSExposed      []string `json:"cors_exposed_headers"`
+	ImageStore       *string  `json:"image_store"`
+	S3Endpoint       *string  `json:"s3_endpoint"`
+	S3Bucket         *string  `json:"s3_bucket"`
+	S3Region         *string  `json:"s3_region"`
+	S3AccessKey      *string  `json:"s3_access_key"`
+	S3SecretKey      *string  `json:"s3_secret_key"`
+	S3UseSSL         *bool    `json:"s3_use_ssl"`
+	ImageGCInterval  *string  `json:"image_gc_interval"`
+	ImageGCGrace     *string  `json:"image_gc_grace_period"`
+}
+
+// setting is a configurable value and where to read it from.
+type setting struct {
+	// flag is the name of the command-line flag, or empty if it cannot be set by a flag.
+	flag string
+	env  string
+	// file returns the value in the config file and whether it is specified.
+	file  func(fc *fileConfig) (string, bool)
+	def   string
+	usage string
+}
+
+var settings = []setting{
+	{
+		flag: "port", env: "MERCARI_PORT", def: defaultPort,
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.Port) },
+		usage: "port number to listen on",
+	},
+	{
+		flag: "db", env: "MERCARI_DB_PATH", def: defaultDBPath,
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.DBPath) },
+		usage: "path to the SQLite database file",
+	},
+	{
+		flag: "image-dir", env: "MERCARI_IMAGE_DIR", def: defaultImageDirPath,
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.ImageDir) },
+		usage: "path to the directory storing images",
+	},
+	{
+		flag: "allowed-origins", env: "MERCARI_ALLOWED_ORIGINS", def: defaultAllowedOrigin,
+		file: func(fc *fileConfig) (string, bool) {
+			return strings.Join(fc.AllowedOrigins, ","), fc.AllowedOrigins != nil
+		},
+		usage: "comma-separated origins or patterns like https://*.example.com allowed to call the API",
+	},
+	{
+		flag: "log-level", env: "MERCARI_LOG_LEVEL", def: defaultLogLevel,
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.LogLevel) },
+		usage: "log level: debug, info, warn or error",
+	},
+	{
+		flag: "log-format", env: "MERCARI_LOG_FORMAT", def: defaultLogFormat,
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.LogFormat) },
+		usage: "log format: json or text",
+	},
+	{
+		flag: "max-upload-bytes", env: "MERCARI_MAX_UPLOAD_BYTES", def: strconv.Itoa(defaultMaxUploadBytes),
+		file: func(fc *fileConfig) (string, bool) {
+			if fc.MaxUploadBytes == nil {
+				return "", false
+			}
+			return strconv.FormatInt(*fc.MaxUploadBytes, 10), true
+		},
+		usage: "maximum size of a request uploading an image, in bytes",
+	},
+	{
+		// the admin token is a secret, so it is not accepted as a flag visible in the process list
+		env:  "MERCARI_ADMIN_TOKEN",
+		file: func(fc *fileConfig) (string, bool) { return deref(fc.AdminToken) },
+	},
+	{
+		flag: "strict-categories", env: "MERCARI_STRICT_CATEGORIES", def: "false",
+		file: func(fc *fileConfig) (string, bool) {
+			if fc.StrictCategories == nil {
+				return "", false
+			}
+			return strconv.FormatBool(*fc.StrictCategories), true
+		},
+		usage: "reject items with unknown categories instead of creating them",
+	},
+	{
+		flag: "cors-max-age", env: "MERCARI_CORS_MAX_AGE", def: defaultCORSMaxAge,
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.CORSMaxAge) },
+		usage: "how long browsers may cache preflight responses, e.g. 10m",
+	},
+	{
+		flag: "cors-allow-credentials", env: "MERCARI_CORS_ALLOW_CREDENTIALS", def: "false",
+		file: func(fc *fileConfig) (string, bool) {
+			if fc.CORSCredentials == nil {
+				return "", false
+			}
+			return strconv.FormatBool(*fc.CORSCredentials), true
+		},
+		usage: "allow cross-origin requests with credentials",
+	},
+	{
+		flag: "cors-exposed-headers", env: "MERCARI_CORS_EXPOSED_HEADERS", def: defaultExposedHeaders,
+		file: func(fc *fileConfig) (string, bool) {
+			return strings.Join(fc.CORSExposed, ","), fc.CORSExposed != nil
+		},
+		usage: "comma-separated response headers scripts on allowed origins may read",
+	},
+	{
+		flag: "image-store", env: "MERCARI_IMAGE_STORE", def: defaultImageStore,
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.ImageStore) },
+		usage: "where to store images: local (in the image dir) or s3",
+	},
+	{
+		flag: "s3-endpoint", env: "MERCARI_S3_ENDPOINT",
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.S3Endpoint) },
+		usage: "host and port of the S3-compatible service, e.g. localhost:9000",
+	},
+	{
+		flag: "s3-bucket", env: "MERCARI_S3_BUCKET",
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.S3Bucket) },
+		usage: "S3 bucket storing images",
+	},
+	{
+		flag: "s3-region", env: "MERCARI_S3_REGION",
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.S3Region) },
+		usage: "S3 region",
+	},
+	{
+		// the S3 credentials are secrets, so they are not accepted as flags
+		env:  "MERCARI_S3_ACCESS_KEY",
+		file: func(fc *fileConfig) (string, bool) { return deref(fc.S3AccessKey) },
+	},
+	{
+		env:  "MERCARI_S3_SECRET_KEY",
+		file: func(fc *fileConfig) (string, bool) { return deref(fc.S3SecretKey) },
+	},
+	{
+		flag: "s3-use-ssl", env: "MERCARI_S3_USE_SSL", def: "true",
+		file: func(fc *fileConfig) (string, bool) {
+			if fc.S3UseSSL == nil {
+				return "", false
+			}
+			return strconv.FormatBool(*fc.S3UseSSL), true
+		},
+		usage: "connect to the S3-compatible service with HTTPS",
+	},
+	{
+		flag: "image-gc-interval", env: "MERCARI_IMAGE_GC_INTERVAL", def: defaultGCInterval,
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.ImageGCInterval) },
+		usage: "how often to delete images no item uses, e.g. 1h, or 0 to disable",
+	},
+	{
+		flag: "image-gc-grace-period", env: "MERCARI_IMAGE_GC_GRACE_PERIOD", def: defaultGCGracePeriod,
+		file:  func(fc *fileConfig) (string, bool) { return deref(fc.ImageGCGrace) },
+		usage: "how long an image must be unused before it is deleted, e.g. 24h",
+	},
+}
+
+// splitList splits a comma-separated list and drops empty elements.
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func deref(s *string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	return *s, true
+}
+
+// LoadServer builds a Server from command-line flags, MERCARI_* environment variables
+// and an optional config file, and validates it.
+// It also returns the arguments remaining after the flags, such as a subcommand.
+func LoadServer(args []string, lookupEnv func(string) (string, bool)) (Server, []string, error) {
+	fs := flag.NewFlagSet("mercari-app", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to a JSON config file (env MERCARI_CONFIG)")
+	flagValues := map[string]*string{}
+	for _, st := range settings {
+		if st.flag != "" {
+			flagValues[st.env] = fs.String(st.flag, st.def, fmt.Sprintf("%s (env %s)", st.usage, st.env))
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return Server{}, nil, err
+	}
+	setFlags := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	// read the config file
+	fc := &fileConfig{}
+	path, ok := lookupEnv("MERCARI_CONFIG")
+	if setFlags["config"] {
+		path, ok = *configPath, true
+	}
+	if ok && path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return Server{}, nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		dec := json.NewDecoder(strings.NewReader(string(b)))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(fc); err != nil {
+			return Server{}, nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+
+	// resolve every setting in order of precedence
+	values := map[string]string{}
+	for _, st := range settings {
+		v := st.def
+		if fv, ok := st.file(fc); ok {
+			v = fv
+		} else if st.env == "MERCARI_ALLOWED_ORIGINS" {
+			if front, ok := lookupEnv("FRONT_URL"); ok {
+				v = front
+			}
+		}
+		if ev, ok := lookupEnv(st.env); ok {
+			v = ev
+		}
+		if st.flag != "" && setFlags[st.flag] {
+			v = *flagValues[st.env]
+		}
+		values[st.env] = v
+	}
+
+	s, err := newServer(values)
+	if err != nil {
+		return Server{}, nil, err
+	}
+	return s, fs.Args(), nil
+}
+
+// newServer converts and validates the resolved settings keyed by environment variable name.
+func newServer(values map[string]string) (Server, error) {
+	var errs []error
+	s := Server{
+		Port:         values["MERCARI_PORT"],
+		DBPath:       values["MERCARI_DB_PATH"],
+		ImageDirPath: values["MERCARI_IMAGE_DIR"],
+		LogFormat:    values["MERCARI_LOG_FORMAT"],
+		AdminToken:   values["MERCARI_ADMIN_TOKEN"],
+	}
+
+	if port, err := strconv.Atoi(s.Port); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535: %q", s.Port))
+	}
+
+	if s.DBPath == "" {
+		errs = append(errs, errors.New("db path is required"))
+	}
+
+	s.ImageStore = values["MERCARI_IMAGE_STORE"]
+	switch s.ImageStore {
+	case "local":
+		if info, err := os.Stat(s.ImageDirPath); err != nil || !info.IsDir() {
+			errs = append(errs, fmt.Errorf("image dir must be an existing directory: %q", s.ImageDirPath))
+		}
+	case "s3":
+		s.S3 = S3Config{
+			Endpoint:  values["MERCARI_S3_ENDPOINT"],
+			Bucket:    values["MERCARI_S3_BUCKET"],
+			Region:    values["MERCARI_S3_REGION"],
+			AccessKey: values["MERCARI_S3_ACCESS_KEY"],
+			SecretKey: values["MERCARI_S3_SECRET_KEY"],
+		}
+		useSSL, err := strconv.ParseBool(values["MERCARI_S3_USE_SSL"])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("s3 use ssl must be true or false: %q", values["MERCARI_S3_USE_SSL"]))
+		}
+		s.S3.UseSSL = useSSL
+		if s.S3.Endpoint == "" || s.S3.Bucket == "" {
+			errs = append(errs, errors.New("s3 endpoint and bucket are required for the s3 image store"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("image store must be local or s3: %q", s.ImageStore))
+	}
+
+	for _, origin := range splitList(values["MERCARI_ALLOWED_ORIGINS"]) {
+		if err := validateOrigin(origin); err != nil {
+			errs = append(errs, fmt.Errorf("allowed origin must be *, like https://example.com or like https://*.example.com: %q", origin))
+			continue
+		}
+		s.AllowedOrigins = append(s.AllowedOrigins, origin)
+	}
+
+	if err := s.LogLevel.UnmarshalText([]byte(values["MERCARI_LOG_LEVEL"])); err != nil {
+		errs = append(errs, fmt.Errorf("log level must be debug, info, warn or error: %q", values["MERCARI_LOG_LEVEL"]))
+	}
+
+	if s.LogFormat != "json" && s.LogFormat != "text" {
+		errs = append(errs, fmt.Errorf("log format must be json or text: %q", s.LogFormat))
+	}
+
+	maxUpload, err := strconv.ParseInt(values["MERCARI_MAX_UPLOAD_BYTES"], 10, 64)
+	if err != nil || maxUpload < 1 {
+		errs = append(errs, fmt.Errorf("max upload bytes must be a positive integer: %q", values["MERCARI_MAX_UPLOAD_BYTES"]))
+	}
+	s.MaxUploadBytes = maxUpload
+
+	strict, err := strconv.ParseBool(values["MERCARI_STRICT_CATEGORIES"])
+	if err != nil {
+		errs = append(errs, fmt.Errorf("strict categories must be true or false: %q", values["MERCARI_STRICT_CATEGORIES"]))
+	}
+	s.StrictCategories = strict
+
+	maxAge, err := time.ParseDuration(values["MERCARI_CORS_MAX_AGE"])
+	if err != nil || maxAge < 0 {
+		errs = append(errs, fmt.Errorf("cors max age must be a non-negative duration like 10m: %q", values["MERCARI_CORS_MAX_AGE"]))
+	}
+	s.CORSMaxAge = maxAge
+
+	credentials, err := strconv.ParseBool(values["MERCARI_CORS_ALLOW_CREDENTIALS"])
+	if err != nil {
+		errs = append(errs, fmt.Errorf("cors allow credentials must be true or false: %q", values["MERCARI_CORS_ALLOW_CREDENTIALS"]))
+	}
+	if credentials && slices.Contains(s.AllowedOrigins, "*") {
+		// any site could make requests with the user's credentials
+		errs = append(errs, errors.New("cors allow credentials cannot be used with the allowed origin *"))
+	}
+	s.CORSAllowCredentials = credentials
+
+	s.CORSExposedHeaders = splitList(values["MERCARI_CORS_EXPOSED_HEADERS"])
+
+	gcInterval, err := time.ParseDuration(values["MERCARI_IMAGE_GC_INTERVAL"])
+	if err != nil || gcInterval < 0 {
+		errs = append(errs, fmt.Errorf("image gc interval must be a non-negative duration like 1h: %q", values["MERCARI_IMAGE_GC_INTERVAL"]))
+	}
+	s.ImageGCInterval = gcInterval
+
+	gcGrace, err := time.ParseDuration(values["MERCARI_IMAGE_GC_GRACE_PERIOD"])
+	if err != nil || gcGrace < minGCGracePeriod {
+		errs = append(errs, fmt.Errorf("image gc grace period must be a duration of at least %s like 24h: %q", minGCGracePeriod, values["MERCARI_IMAGE_GC_GRACE_PERIOD"]))
+	}
+	s.ImageGCGracePeriod = gcGrace
+
+	if err := errors.Join(errs...); err != nil {
+		return Server{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return s, nil
+}
+
+// newImageStore creates the configured image store.
+func (s Server) newImageStore(ctx context.Context) (ImageStore, error) {
+	if s.ImageStore == "s3" {
+		return NewS3ImageStore(ctx, s.S3)
+	}
+	return NewLocalImageStore(s.ImageDirPath), nil
+}
+
+// newLogger creates a logger writing in the configured format and level.
+// Records logged with the context of a request include its request ID.
+func (s Server) newLogger() *slog.Logger {
+	opts := &slog.HandlerOptions{Level: s.LogLevel}
+	if s.LogFormat == "text" {
+		return slog.New(requestIDHandler{slog.NewTextHandler(os.Stderr, opts)})
+	}
+	return slog.New(requestIDHandler{slog.NewJSONHandler(os.Stderr, opts)})
+}
@@ -0,0 +1,256 @@
+package app
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestLoadServer(t *testing.T) {
+	t.Parallel()
+
+	imageDir := t.TempDir()
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(configPath, []byte(`{
+		"port": "8000",
+		"db_path": "file.sqlite3",
+		"image_dir": "`+imageDir+`",
+		"log_level": "warn",
+		"allowed_origins": ["https://file.example.com"]
+	}`), 0644)
+	if err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+
+	defaults := Server{
+		Port:               defaultPort,
+		DBPath:             defaultDBPath,
+		ImageDirPath:       imageDir,
+		ImageStore:         defaultImageStore,
+		AllowedOrigins:     []string{defaultAllowedOrigin},
+		CORSMaxAge:         10 * time.Minute,
+		CORSExposedHeaders: []string{defaultExposedHeaders},
+		LogLevel:           slog.LevelDebug,
+		LogFormat:          defaultLogFormat,
+		MaxUploadBytes:     defaultMaxUploadBytes,
+		ImageGCInterval:    time.Hour,
+		ImageGCGracePeriod: 24 * time.Hour,
+	}
+
+	type wants struct {
+		server Server
+		args   []string
+		err    bool
+	}
+	cases := map[string]struct {
+		args []string
+		env  map[string]string
+		wants
+	}{
+		"ok: defaults": {
+			args: []string{"-image-dir", imageDir},
+			wants: wants{
+				server: defaults,
+				args:   []string{},
+			},
+		},
+		"ok: flags override env and env overrides file": {
+			args: []string{"-config", configPath, "-port", "7000", "migrate", "up"},
+			env: map[string]string{
+				"MERCARI_PORT":            "6000",
+				"MERCARI_DB_PATH":         "env.sqlite3",
+				"MERCARI_ALLOWED_ORIGINS": "https://a.example.com, https://b.example.com",
+				"MERCARI_ADMIN_TOKEN":     "secret",
+			},
+			wants: wants{
+				server: Server{
+					Port:               "7000",
+					DBPath:             "env.sqlite3",
+					ImageDirPath:       imageDir,
+					ImageStore:         defaultImageStore,
+					AllowedOrigins:     []string{"https://a.example.com", "https://b.example.com"},
+					CORSMaxAge:         10 * time.Minute,
+					CORSExposedHeaders: []string{defaultExposedHeaders},
+					LogLevel:           slog.LevelWarn,
+					LogFormat:          defaultLogFormat,
+					MaxUploadBytes:     defaultMaxUploadBytes,
+					AdminToken:         "secret",
+					ImageGCInterval:    time.Hour,
+					ImageGCGracePeriod: 24 * time.Hour,
+				},
+				args: []string{"migrate", "up"},
+			},
+		},
+		"ok: config file from env and legacy FRONT_URL": {
+			env: map[string]string{
+				"MERCARI_CONFIG":            configPath,
+				"MERCARI_STRICT_CATEGORIES": "true",
+				"FRONT_URL":                 "https://front.example.com",
+			},
+			wants: wants{
+				server: Server{
+					Port:               "8000",
+					DBPath:             "file.sqlite3",
+					ImageDirPath:       imageDir,
+					ImageStore:         defaultImageStore,
+					AllowedOrigins:     []string{"https://file.example.com"},
+					CORSMaxAge:         10 * time.Minute,
+					CORSExposedHeaders: []string{defaultExposedHeaders},
+					LogLevel:           slog.LevelWarn,
+					LogFormat:          defaultLogFormat,
+					MaxUploadBytes:     defaultMaxUploadBytes,
+					StrictCategories:   true,
+					ImageGCInterval:    time.Hour,
+					ImageGCGracePeriod: 24 * time.Hour,
+				},
+				args: []string{},
+			},
+		},
+		"ng: invalid values": {
+			args: []string{"-image-dir", imageDir, "-port", "http", "-log-level", "verbose", "-max-upload-bytes", "-1"},
+			wants: wants{
+				err: true,
+			},
+		},
+		"ng: image dir does not exist": {
+			args: []string{"-image-dir", filepath.Join(imageDir, "missing")},
+			wants: wants{
+				err: true,
+			},
+		},
+		"ng: invalid origin": {
+			args: []string{"-image-dir", imageDir, "-allowed-origins", "localhost:3000"},
+			wants: wants{
+				err: true,
+			},
+		},
+		"ok: CORS settings": {
+			args: []string{"-image-dir", imageDir, "-allowed-origins", "https://*.preview.example.com,https://example.com", "-cors-max-age", "1h", "-cors-allow-credentials", "true"},
+			env: map[string]string{
+				"MERCARI_CORS_EXPOSED_HEADERS": "X-Request-ID, ETag",
+			},
+			wants: wants{
+				server: func() Server {
+					s := defaults
+					s.AllowedOrigins = []string{"https://*.preview.example.com", "https://example.com"}
+					s.CORSMaxAge = time.Hour
+					s.CORSAllowCredentials = true
+					s.CORSExposedHeaders = []string{"X-Request-ID", "ETag"}
+					return s
+				}(),
+				args: []string{},
+			},
+		},
+		"ng: invalid origin pattern": {
+			args: []string{"-image-dir", imageDir, "-allowed-origins", "https://*.*.example.com"},
+			wants: wants{
+				err: true,
+			},
+		},
+		"ng: credentials with any origin": {
+			args: []string{"-image-dir", imageDir, "-allowed-origins", "*", "-cors-allow-credentials", "true"},
+			wants: wants{
+				err: true,
+			},
+		},
+		"ok: s3 image store": {
+			args: []string{"-image-store", "s3", "-image-dir", filepath.Join(imageDir, "missing"), "-s3-endpoint", "localhost:9000", "-s3-bucket", "images", "-s3-use-ssl", "false"},
+			env: map[string]string{
+				"MERCARI_S3_ACCESS_KEY": "access",
+				"MERCARI_S3_SECRET_KEY": "secret",
+			},
+			wants: wants{
+				server: func() Server {
+					s := defaults
+					s.ImageDirPath = filepath.Join(imageDir, "missing")
+					s.ImageStore = "s3"
+					s.S3 = S3Config{
+						Endpoint:  "localhost:9000",
+						Bucket:    "images",
+						AccessKey: "access",
+						SecretKey: "secret",
+					}
+					return s
+				}(),
+				args: []string{},
+			},
+		},
+		"ng: s3 image store without a bucket": {
+			args: []string{"-image-store", "s3", "-s3-endpoint", "localhost:9000"},
+			wants: wants{
+				err: true,
+			},
+		},
+		"ng: unknown image store": {
+			args: []string{"-image-dir", imageDir, "-image-store", "ftp"},
+			wants: wants{
+				err: true,
+			},
+		},
+		"ok: image gc settings": {
+			args: []string{"-image-dir", imageDir, "-image-gc-interval", "0"},
+			env: map[string]string{
+				"MERCARI_IMAGE_GC_GRACE_PERIOD": "1h30m",
+			},
+			wants: wants{
+				server: func() Server {
+					s := defaults
+					s.ImageGCInterval = 0
+					s.ImageGCGracePeriod = 90 * time.Minute
+					return s
+				}(),
+				args: []string{},
+			},
+		},
+		"ng: invalid image gc settings": {
+			args: []string{"-image-dir", imageDir, "-image-gc-interval", "-1h", "-image-gc-grace-period", "a day"},
+			wants: wants{
+				err: true,
+			},
+		},
+		"ng: image gc grace period below the minimum": {
+			args: []string{"-image-dir", imageDir, "-image-gc-grace-period", "0"},
+			wants: wants{
+				err: true,
+			},
+		},
+		"ng: config file does not exist": {
+			args: []string{"-config", filepath.Join(imageDir, "missing.json")},
+			wants: wants{
+				err: true,
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			lookupEnv := func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+			got, args, err := LoadServer(tt.args, lookupEnv)
+			if err != nil {
+				if !tt.err {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if tt.err {
+				t.Fatalf("expected an error")
+			}
+			if diff := cmp.Diff(tt.wants.server, got); diff != "" {
+				t.Errorf("unexpected server (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wants.args, args, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("unexpected args (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures which cross-origin requests browsers may send to the API.
+// See https://fetch.spec.whatwg.org/#http-cors-protocol for the protocol.
+type CORSConfig struct {
+	// AllowedOrigins are the origins allowed to call the API.
+	// An origin is either "*", an exact origin such as https://example.com,
+	// or a pattern such as https://*.example.com where * matches a single DNS label.
+	AllowedOrigins []string
+	// AllowedHeaders are the request headers browsers may send.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers scripts may read.
+	ExposedHeaders []string
+	// MaxAge is how long browsers may cache a preflight response. 0 omits the header.
+	MaxAge time.Duration
+	// AllowCredentials allows requests with cookies and Authorization headers.
+	AllowCredentials bool
+}
+
+// corsRequestHeaders are the request headers the API reads beyond the CORS-safelisted ones.
+var corsRequestHeaders = []string{"Authorization", "Content-Type", "X-Admin-Token", "X-Request-ID"}
+
+// corsMethods are the methods looked up in the mux to answer preflight requests.
+var corsMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// validateOrigin checks that origin is "*", an origin, or an origin pattern.
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	if strings.Count(origin, "*") > 1 {
+		return errors.New("an origin pattern may contain only one *")
+	}
+	// replace the wildcard with a label so that the pattern parses as a URL
+	u, err := url.Parse(strings.Replace(origin, "*", "x", 1))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("an origin must be like https://example.com")
+	}
+	if i := strings.Index(origin, "*"); i >= 0 && i < len(u.Scheme+"://") {
+		return errors.New("the wildcard of an origin pattern must be in the host")
+	}
+	return nil
+}
+
+// matchOrigin reports whether origin matches the allowed origin or pattern.
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" || pattern == origin {
+		return true
+	}
+	prefix, suffix, ok := strings.Cut(pattern, "*")
+	if !ok || len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	label := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(label, "./:@")
+}
+
+// corsMiddleware handles CORS for the routes registered in mux.
+// Preflight requests are answered with the methods mux has routes for,
+// and get 404 if no route matches the path.
+func corsMiddleware(next http.Handler, mux *http.ServeMux, cfg CORSConfig) http.Handler {
+	allowedHeaders := strings.Join(cfg.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(cfg.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// responses differ by origin, so caches must not share them across origins
+		w.Header().Add("Vary", "Origin")
+		origin := r.Header.Get("Origin")
+		allowed := origin != "" && slices.ContainsFunc(cfg.AllowedOrigins, func(p string) bool { return matchOrigin(p, origin) })
+
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if !preflight {
+			if allowed {
+				setAllowOrigin(w, origin, cfg.AllowCredentials)
+				if exposedHeaders != "" {
+					w.Header().Set("Access-Control-Expose-Headers", exposedHeaders)
+				}
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Access-Control-Request-Method")
+		w.Header().Add("Vary", "Access-Control-Request-Headers")
+		methods := routeMethods(mux, r)
+		if len(methods) == 0 {
+			writeJSONError(w, http.StatusNotFound, "no route for "+r.URL.Path)
+			return
+		}
+		if !allowed {
+			writeJSONError(w, http.StatusForbidden, "origin not allowed")
+			return
+		}
+		setAllowOrigin(w, origin, cfg.AllowCredentials)
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		if allowedHeaders != "" {
+			w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+		}
+		if cfg.MaxAge > 0 {
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// setAllowOrigin allows origin to read the response.
+func setAllowOrigin(w http.ResponseWriter, origin string, credentials bool) {
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if credentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// routeMethods returns the methods mux has routes for at the path of r.
+func routeMethods(mux *http.ServeMux, r *http.Request) []string {
+	var methods []string
+	for _, m := range corsMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = m
+		if _, pattern := mux.Handler(probe); pattern != "" {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
@@ -0,0 +1,203 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatchOrigin(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		pattern string
+		origin  string
+		want    bool
+	}{
+		"ok: exact":                    {pattern: "https://example.com", origin: "https://example.com", want: true},
+		"ok: any":                      {pattern: "*", origin: "https://example.com", want: true},
+		"ok: wildcard label":           {pattern: "https://*.example.com", origin: "https://pr-1.example.com", want: true},
+		"ok: wildcard in label":        {pattern: "https://app-*.vercel.app", origin: "https://app-git-main.vercel.app", want: true},
+		"ng: different scheme":         {pattern: "https://example.com", origin: "http://example.com", want: false},
+		"ng: different port":           {pattern: "http://localhost:3000", origin: "http://localhost:3001", want: false},
+		"ng: wildcard matches nothing": {pattern: "https://*.example.com", origin: "https://.example.com", want: false},
+		"ng: wildcard matches a dot":   {pattern: "https://*.example.com", origin: "https://a.b.example.com", want: false},
+		"ng: other domain":             {pattern: "https://*.example.com", origin: "https://example.com.evil.com", want: false},
+		"ng: suffix of the domain":     {pattern: "https://*.example.com", origin: "https://evilexample.com", want: false},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := matchOrigin(tt.pattern, tt.origin); got != tt.want {
+				t.Errorf("matchOrigin(%q, %q) = %v, want %v", tt.pattern, tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateOrigin(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		origin string
+		err    bool
+	}{
+		"ok: any":                {origin: "*"},
+		"ok: origin":             {origin: "http://localhost:3000"},
+		"ok: pattern":            {origin: "https://*.example.com"},
+		"ng: no scheme":          {origin: "localhost:3000", err: true},
+		"ng: path":               {origin: "https://example.com/app", err: true},
+		"ng: two wildcards":      {origin: "https://*.*.example.com", err: true},
+		"ng: wildcard scheme":    {origin: "*://example.com", err: true},
+		"ng: unsupported scheme": {origin: "ftp://example.com", err: true},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateOrigin(tt.origin)
+			if (err != nil) != tt.err {
+				t.Errorf("validateOrigin(%q) = %v, want error: %v", tt.origin, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) }
+	mux.HandleFunc("GET /items", ok)
+	mux.HandleFunc("POST /items", ok)
+	mux.HandleFunc("PATCH /items/{id}", ok)
+	mux.HandleFunc("DELETE /items/{id}", ok)
+
+	cfg := CORSConfig{
+		AllowedOrigins: []string{"http://localhost:3000", "https://*.preview.example.com"},
+		AllowedHeaders: []string{"Content-Type", "X-Request-ID"},
+		ExposedHeaders: []string{"X-Request-ID"},
+		MaxAge:         10 * time.Minute,
+	}
+
+	type wants struct {
+		code    int
+		headers map[string]string
+	}
+	cases := map[string]struct {
+		method        string
+		path          string
+		origin        string
+		requestMethod string
+		credentials   bool
+		wants
+	}{
+		"ok: preflight lists the methods of the route": {
+			method: "OPTIONS", path: "/items/1", origin: "http://localhost:3000", requestMethod: "PATCH",
+			wants: wants{
+				code: http.StatusNoContent,
+				headers: map[string]string{
+					"Access-Control-Allow-Origin":      "http://localhost:3000",
+					"Access-Control-Allow-Methods":     "PATCH, DELETE",
+					"Access-Control-Allow-Headers":     "Content-Type, X-Request-ID",
+					"Access-Control-Max-Age":           "600",
+					"Access-Control-Allow-Credentials": "",
+				},
+			},
+		},
+		"ok: preflight from a preview origin": {
+			method: "OPTIONS", path: "/items", origin: "https://pr-42.preview.example.com", requestMethod: "POST",
+			wants: wants{
+				code: http.StatusNoContent,
+				headers: map[string]string{
+					"Access-Control-Allow-Origin":  "https://pr-42.preview.example.com",
+					"Access-Control-Allow-Methods": "GET, HEAD, POST",
+				},
+			},
+		},
+		"ok: preflight with credentials": {
+			method: "OPTIONS", path: "/items", origin: "http://localhost:3000", requestMethod: "POST", credentials: true,
+			wants: wants{
+				code: http.StatusNoContent,
+				headers: map[string]string{
+					"Access-Control-Allow-Origin":      "http://localhost:3000",
+					"Access-Control-Allow-Credentials": "true",
+				},
+			},
+		},
+		"ng: preflight to an unknown route": {
+			method: "OPTIONS", path: "/unknown", origin: "http://localhost:3000", requestMethod: "GET",
+			wants: wants{
+				code:    http.StatusNotFound,
+				headers: map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+			},
+		},
+		"ng: preflight from a disallowed origin": {
+			method: "OPTIONS", path: "/items", origin: "https://evil.example.com", requestMethod: "POST",
+			wants: wants{
+				code:    http.StatusForbidden,
+				headers: map[string]string{"Access-Control-Allow-Origin": "", "Access-Control-Allow-Methods": ""},
+			},
+		},
+		"ok: simple request from an allowed origin": {
+			method: "GET", path: "/items", origin: "http://localhost:3000",
+			wants: wants{
+				code: http.StatusOK,
+				headers: map[string]string{
+					"Access-Control-Allow-Origin":   "http://localhost:3000",
+					"Access-Control-Expose-Headers": "X-Request-ID",
+					"Vary":                          "Origin",
+				},
+			},
+		},
+		"ok: simple request from a disallowed origin is served without CORS headers": {
+			method: "GET", path: "/items", origin: "https://evil.example.com",
+			wants: wants{
+				code:    http.StatusOK,
+				headers: map[string]string{"Access-Control-Allow-Origin": "", "Vary": "Origin"},
+			},
+		},
+		"ng: OPTIONS without preflight headers is left to the mux": {
+			method: "OPTIONS", path: "/items", origin: "http://localhost:3000",
+			wants: wants{
+				code:    http.StatusMethodNotAllowed,
+				headers: map[string]string{"Access-Control-Allow-Methods": ""},
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			c := cfg
+			c.AllowCredentials = tt.credentials
+			h := corsMiddleware(mux, mux, c)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.requestMethod != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.requestMethod)
+			}
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			if res.Code != tt.code {
+				t.Errorf("expected status code %d, got %d", tt.code, res.Code)
+			}
+			got := map[string]string{}
+			for k := range tt.headers {
+				got[k] = res.Header().Get(k)
+			}
+			if diff := cmp.Diff(tt.headers, got); diff != "" {
+				t.Errorf("unexpected headers (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
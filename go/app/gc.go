@@ -0,0 +1,124 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// imageCollector deletes images that no item has used for a grace period.
+// The grace period keeps an image that was just uploaded, or whose item was just changed,
+// from being deleted before an item refers to it again.
+type imageCollector struct {
+	repo  ItemRepository
+	store ImageStore
+	// grace is how long an image must be unused before it is deleted.
+	grace time.Duration
+	// metrics records the collected images. It may be nil.
+	metrics *Metrics
+}
+
+// collect deletes the images no item has used since grace before now, together with their resized variants.
+// It returns the number of deleted images. Images that fail to be deleted are skipped
+// and reported in the returned error.
+func (c *imageCollector) collect(ctx context.Context, now time.Time) (int, error) {
+	before := now.Add(-c.grace)
+	images, err := c.repo.ListOrphanImages(ctx, before)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	var errs []error
+	for _, img := range images {
+		// the files are deleted together with the record, so that an image used again in the meantime is kept
+		// and an image uploaded again in the meantime is stored again
+		deleted, err := c.repo.DeleteOrphanImage(ctx, img.Name, before, func(ctx context.Context) error {
+			return c.deleteFiles(ctx, img.Name)
+		})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if deleted {
+			n++
+		}
+	}
+	c.metrics.imagesCollected(n)
+	return n, errors.Join(errs...)
+}
+
+// deleteFiles deletes the image name and its resized variants from the store.
+func (c *imageCollector) deleteFiles(ctx context.Context, name string) error {
+	names := []string{name}
+	if f, ok := imageFormatByExt(name); ok {
+		for _, w := range thumbnailWidths {
+			names = append(names, variantName(name, f, w))
+		}
+	}
+	for _, name := range names {
+		if err := c.store.Delete(ctx, name); err != nil {
+			return fmt.Errorf("failed to delete image %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// run collects orphan images every interval until ctx is done.
+// It returns immediately if interval is not positive.
+func (c *imageCollector) run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			n, err := c.collect(ctx, now)
+			if err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "failed to collect orphan images: ", "error", err)
+			}
+			if n > 0 {
+				slog.InfoContext(ctx, "collected orphan images", "count", n)
+			}
+		}
+	}
+}
+
+// RunGC runs the gc subcommand against the configured database and image store
+// and returns the exit code. It deletes the images no item has used for the grace period once.
+func (s Server) RunGC(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: gc")
+		return 2
+	}
+
+	ctx := context.Background()
+	itemRepo, err := NewItemRepository(s.dsn(), nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer itemRepo.Close()
+	imgStore, err := s.newImageStore(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	c := &imageCollector{repo: itemRepo, store: imgStore, grace: s.ImageGCGracePeriod}
+	n, err := c.collect(ctx, time.Now())
+	fmt.Printf("deleted %d orphan image(s)\n", n)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,309 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestImageCollectorCollect(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	orphans := []Image{{Name: "abc.png"}, {Name: "def.png"}}
+	// deleteOrphan deletes the record of an orphan image and its files, as the repository does
+	deleteOrphan := func(ctx context.Context, name string, before time.Time, deleteFiles func(context.Context) error) (bool, error) {
+		return true, deleteFiles(ctx)
+	}
+
+	cases := map[string]struct {
+		injector func(m *MockItemRepository)
+		n        int
+		kept     []string
+		err      bool
+	}{
+		"ok: orphans are deleted": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListOrphanImages(gomock.Any(), before).Return(orphans, nil)
+				m.EXPECT().DeleteOrphanImage(gomock.Any(), "abc.png", before, gomock.Any()).DoAndReturn(deleteOrphan)
+				m.EXPECT().DeleteOrphanImage(gomock.Any(), "def.png", before, gomock.Any()).DoAndReturn(deleteOrphan)
+			},
+			n: 2,
+		},
+		"ok: an image used again is kept": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListOrphanImages(gomock.Any(), before).Return(orphans, nil)
+				m.EXPECT().DeleteOrphanImage(gomock.Any(), "abc.png", before, gomock.Any()).Return(false, nil)
+				m.EXPECT().DeleteOrphanImage(gomock.Any(), "def.png", before, gomock.Any()).DoAndReturn(deleteOrphan)
+			},
+			n:    1,
+			kept: []string{"abc.png", "abc_w150.png"},
+		},
+		"ng: failed to delete a record": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListOrphanImages(gomock.Any(), before).Return(orphans, nil)
+				m.EXPECT().DeleteOrphanImage(gomock.Any(), "abc.png", before, gomock.Any()).Return(false, errors.New("failed to delete"))
+				m.EXPECT().DeleteOrphanImage(gomock.Any(), "def.png", before, gomock.Any()).DoAndReturn(deleteOrphan)
+			},
+			n:    1,
+			kept: []string{"abc.png", "abc_w150.png"},
+			err:  true,
+		},
+		"ng: failed to list": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListOrphanImages(gomock.Any(), before).Return(nil, errors.New("failed to list"))
+			},
+			kept: []string{"abc.png", "abc_w150.png", "def.png"},
+			err:  true,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+
+			ctx := context.Background()
+			store := NewMemoryImageStore()
+			for _, name := range []string{"abc.png", "abc_w150.png", "def.png"} {
+				if err := store.Put(ctx, name, bytes.NewReader(nil), 0, "image/png"); err != nil {
+					t.Fatalf("failed to put image: %v", err)
+				}
+			}
+
+			c := &imageCollector{repo: mockIR, store: store, grace: time.Hour}
+			n, err := c.collect(ctx, now)
+			if (err != nil) != tt.err {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if n != tt.n {
+				t.Errorf("expected %d deleted images, got %d", tt.n, n)
+			}
+			for _, name := range []string{"abc.png", "abc_w150.png", "def.png"} {
+				want := false
+				for _, k := range tt.kept {
+					want = want || k == name
+				}
+				if ok, _ := store.Exists(ctx, name); ok != want {
+					t.Errorf("expected %s to exist: %v, got %v", name, want, ok)
+				}
+			}
+		})
+	}
+}
+
+func TestImageCollectorE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	repo := &itemRepository{db: db}
+	store := NewLocalImageStore(t.TempDir())
+	ctx := context.Background()
+
+	// store images the way uploads do
+	names := map[string]string{}
+	images := map[string][]byte{
+		"kept":        newSizedTestImage(t, "image/png", 500, 100),
+		"replaced":    newSizedTestImage(t, "image/jpeg", 500, 100),
+		"unused":      newSizedTestImage(t, "image/gif", 500, 100),
+		"saved again": newSizedTestImage(t, "image/png", 600, 100),
+	}
+	for key, data := range images {
+		upload, err := saveUpload(t.TempDir(), bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to save upload: %v", err)
+		}
+		h := &Handlers{imgStore: store, itemRepo: repo}
+		filePath, err := h.storeItemImage(ctx, upload)
+		if err != nil {
+			t.Fatalf("failed to store image: %v", err)
+		}
+		names[key] = filePath
+	}
+
+	// kept is used by a soft-deleted item, replaced is no longer used by any item
+	if err := repo.Insert(ctx, &Item{Name: "jacket", Category: "fashion", ImageName: names["replaced"]}); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+	if err := repo.Update(ctx, &Item{ID: 1, Name: "jacket", Category: "fashion", ImageName: names["kept"]}); err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+	if err := repo.Insert(ctx, &Item{Name: "jeans", Category: "fashion", ImageName: names["kept"]}); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+	if err := repo.Delete(ctx, 2); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	counts := map[string]int{}
+	rows, err := db.Query("SELECT name, ref_count FROM images")
+	if err != nil {
+		t.Fatalf("failed to query images: %v", err)
+	}
+	for rows.Next() {
+		var name string
+		var n int
+		if err := rows.Scan(&name, &n); err != nil {
+			t.Fatalf("failed to scan row: %v", err)
+		}
+		counts[path.Join(imageURLDir, name)] = n
+	}
+	rows.Close()
+	want := map[string]int{names["kept"]: 2, names["replaced"]: 0, names["unused"]: 0, names["saved again"]: 0}
+	for name, n := range want {
+		if counts[name] != n {
+			t.Errorf("expected %s to be used by %d items, got %d", name, n, counts[name])
+		}
+	}
+
+	// nothing is deleted within the grace period
+	c := &imageCollector{repo: repo, store: store, grace: time.Hour}
+	n, err := c.collect(ctx, time.Now())
+	if err != nil || n != 0 {
+		t.Fatalf("collect() = %d, %v, want 0", n, err)
+	}
+
+	// after the grace period, saving an image again restarts its grace period
+	if _, err := db.Exec("UPDATE images SET unreferenced_at = '2000-01-01 00:00:00' WHERE ref_count = 0"); err != nil {
+		t.Fatalf("failed to age images: %v", err)
+	}
+	h := &Handlers{imgStore: store, itemRepo: repo}
+	upload, err := saveUpload(t.TempDir(), bytes.NewReader(images["saved again"]))
+	if err != nil {
+		t.Fatalf("failed to save upload: %v", err)
+	}
+	if _, err := h.storeItemImage(ctx, upload); err != nil {
+		t.Fatalf("failed to store image: %v", err)
+	}
+
+	n, err = c.collect(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("failed to collect images: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 deleted images, got %d", n)
+	}
+	for key, wantExists := range map[string]bool{"kept": true, "replaced": false, "unused": false, "saved again": true} {
+		name := path.Base(names[key])
+		if ok, err := store.Exists(ctx, name); err != nil || ok != wantExists {
+			t.Errorf("expected %s image to exist: %v, got %v, %v", key, wantExists, ok, err)
+		}
+		// variants are deleted with the original
+		f, _ := imageFormatByExt(name)
+		if ok, err := store.Exists(ctx, variantName(name, f, 150)); err != nil || ok != wantExists {
+			t.Errorf("expected %s variant to exist: %v, got %v, %v", key, wantExists, ok, err)
+		}
+	}
+}
+
+// hookedImageStore is an ImageStore that calls onDelete before deleting an image.
+type hookedImageStore struct {
+	ImageStore
+	onDelete func(name string)
+}
+
+func (s *hookedImageStore) Delete(ctx context.Context, name string) error {
+	s.onDelete(name)
+	return s.ImageStore.Delete(ctx, name)
+}
+
+func TestImageCollectorReuploadE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	repo := &itemRepository{db: db}
+	ctx := context.Background()
+	data := newSizedTestImage(t, "image/png", 500, 100)
+	reupload := func(h *Handlers) (string, error) {
+		upload, err := saveUpload(t.TempDir(), bytes.NewReader(data))
+		if err != nil {
+			return "", err
+		}
+		defer upload.discard()
+		return h.storeItemImage(ctx, upload)
+	}
+
+	// the same image is uploaded again while the collector deletes its files
+	var once sync.Once
+	done := make(chan error, 1)
+	store := &hookedImageStore{ImageStore: NewLocalImageStore(t.TempDir())}
+	h := &Handlers{imgStore: store, itemRepo: repo}
+	store.onDelete = func(string) {
+		once.Do(func() {
+			go func() {
+				_, err := reupload(h)
+				done <- err
+			}()
+			// give the upload the chance to finish before the files are deleted
+			select {
+			case err := <-done:
+				done <- err
+			case <-time.After(200 * time.Millisecond):
+			}
+		})
+	}
+
+	filePath, err := reupload(h)
+	if err != nil {
+		t.Fatalf("failed to store image: %v", err)
+	}
+	if _, err := db.Exec("UPDATE images SET unreferenced_at = '2000-01-01 00:00:00'"); err != nil {
+		t.Fatalf("failed to age images: %v", err)
+	}
+
+	c := &imageCollector{repo: repo, store: store, grace: time.Hour}
+	n, err := c.collect(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("failed to collect images: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 deleted image, got %d", n)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("failed to store image again: %v", err)
+	}
+
+	// the image uploaded again is recorded and stored, so that an item can use it
+	name := path.Base(filePath)
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM images WHERE name = ?", name).Scan(&count); err != nil {
+		t.Fatalf("failed to count images: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected the image to be recorded again, got %d records", count)
+	}
+	if ok, err := store.Exists(ctx, name); err != nil || !ok {
+		t.Errorf("expected the image uploaded again to exist, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// readyCheckTimeout limits how long each readiness check may take.
+const readyCheckTimeout = 2 * time.Second
+
+const (
+	checkStatusOK   = "ok"
+	checkStatusFail = "fail"
+)
+
+// HealthResponse is the body of /healthz and /readyz.
+type HealthResponse struct {
+	// Status is "ok" if every check passed, and "fail" otherwise.
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// CheckResult is the outcome of a single readiness check.
+type CheckResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Migrations lists the applied migrations. It is only set by the migrations check.
+	Migrations []AppliedMigration `json:"migrations,omitempty"`
+}
+
+// AppliedMigration is a migration reported by /readyz.
+type AppliedMigration struct {
+	Version   int       `json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Healthz reports that the process is up and serving requests.
+// It does not check any dependency, so it can be used as a liveness probe.
+func (s *Handlers) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, HealthResponse{Status: checkStatusOK})
+}
+
+// Readyz reports whether the server can handle requests:
+// the database is reachable and fully migrated, and the image store is usable.
+// It responds 503 if any check fails.
+func (s *Handlers) Readyz(w http.ResponseWriter, r *http.Request) {
+	resp := HealthResponse{Status: checkStatusOK, Checks: map[string]CheckResult{}}
+	record := func(name string, res CheckResult, err error) {
+		res.Status = checkStatusOK
+		if err != nil {
+			slog.WarnContext(r.Context(), "readiness check failed: ", "check", name, "error", err)
+			res.Status = checkStatusFail
+			res.Error = err.Error()
+			resp.Status = checkStatusFail
+		}
+		resp.Checks[name] = res
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout)
+	defer cancel()
+
+	record("database", CheckResult{}, s.itemRepo.Ping(ctx))
+	record("image_store", CheckResult{}, checkImageStoreWritable(ctx, s.imgStore))
+	record("default_image", CheckResult{}, checkImageExists(ctx, s.imgStore, defaultImageName))
+	applied, err := s.checkMigrations(ctx)
+	record("migrations", CheckResult{Migrations: applied}, err)
+
+	code := http.StatusOK
+	if resp.Status != checkStatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, r, code, resp)
+}
+
+// checkMigrations returns the applied migrations, and an error if any migration is pending.
+func (s *Handlers) checkMigrations(ctx context.Context) ([]AppliedMigration, error) {
+	statuses, err := s.itemRepo.MigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var applied []AppliedMigration
+	var pending []int
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			pending = append(pending, st.Version)
+			continue
+		}
+		applied = append(applied, AppliedMigration{Version: st.Version, Name: st.Name, AppliedAt: *st.AppliedAt})
+	}
+	if len(pending) > 0 {
+		return applied, fmt.Errorf("pending migrations: %v", pending)
+	}
+	return applied, nil
+}
+
+// readyProbeImage is the name of the image written and deleted to check the image store.
+const readyProbeImage = ".readyz-probe"
+
+// checkImageStoreWritable checks that an image can be stored and deleted.
+func checkImageStoreWritable(ctx context.Context, store ImageStore) error {
+	err := store.Put(ctx, readyProbeImage, strings.NewReader("ok"), 2, "text/plain")
+	if err != nil {
+		return fmt.Errorf("image store is not writable: %w", err)
+	}
+	return store.Delete(ctx, readyProbeImage)
+}
+
+// checkImageExists checks that the image name exists in store.
+func checkImageExists(ctx context.Context, store ImageStore, name string) error {
+	exists, err := store.Exists(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%s does not exist", name)
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHealthz(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest("GET", "/healthz", nil)
+	res := httptest.NewRecorder()
+
+	h := &Handlers{}
+	h.Healthz(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Errorf("expected status code %d, got %d", http.StatusOK, res.Code)
+	}
+	var got HealthResponse
+	if err := json.NewDecoder(res.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response body: %v", err)
+	}
+	if diff := cmp.Diff(HealthResponse{Status: "ok"}, got); diff != "" {
+		t.Errorf("unexpected response body (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	t.Parallel()
+
+	appliedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	migrated := []MigrationStatus{
+		{Migration: Migration{Version: 1, Name: "create_items"}, AppliedAt: &appliedAt},
+		{Migration: Migration{Version: 2, Name: "add_item_timestamps"}, AppliedAt: &appliedAt},
+	}
+	applied := []AppliedMigration{
+		{Version: 1, Name: "create_items", AppliedAt: appliedAt},
+		{Version: 2, Name: "add_item_timestamps", AppliedAt: appliedAt},
+	}
+
+	type wants struct {
+		code int
+		// statuses are the statuses of each check
+		statuses map[string]string
+	}
+	cases := map[string]struct {
+		injector     func(m *MockItemRepository)
+		noDefaultImg bool
+		missingDir   bool
+		wants
+	}{
+		"ok: every check passes": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Ping(gomock.Any()).Return(nil)
+				m.EXPECT().MigrationStatus(gomock.Any()).Return(migrated, nil)
+			},
+			wants: wants{
+				code:     http.StatusOK,
+				statuses: map[string]string{"database": "ok", "image_store": "ok", "default_image": "ok", "migrations": "ok"},
+			},
+		},
+		"ng: database is unreachable": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Ping(gomock.Any()).Return(errors.New("database is closed"))
+				m.EXPECT().MigrationStatus(gomock.Any()).Return(nil, errors.New("database is closed"))
+			},
+			wants: wants{
+				code:     http.StatusServiceUnavailable,
+				statuses: map[string]string{"database": "fail", "image_store": "ok", "default_image": "ok", "migrations": "fail"},
+			},
+		},
+		"ng: pending migration": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Ping(gomock.Any()).Return(nil)
+				m.EXPECT().MigrationStatus(gomock.Any()).Return(append(migrated[:1:1], MigrationStatus{Migration: Migration{Version: 2, Name: "add_item_timestamps"}}), nil)
+			},
+			wants: wants{
+				code:     http.StatusServiceUnavailable,
+				statuses: map[string]string{"database": "ok", "image_store": "ok", "default_image": "ok", "migrations": "fail"},
+			},
+		},
+		"ng: default image is missing": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Ping(gomock.Any()).Return(nil)
+				m.EXPECT().MigrationStatus(gomock.Any()).Return(migrated, nil)
+			},
+			noDefaultImg: true,
+			wants: wants{
+				code:     http.StatusServiceUnavailable,
+				statuses: map[string]string{"database": "ok", "image_store": "ok", "default_image": "fail", "migrations": "ok"},
+			},
+		},
+		"ng: image store is not writable": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Ping(gomock.Any()).Return(nil)
+				m.EXPECT().MigrationStatus(gomock.Any()).Return(migrated, nil)
+			},
+			missingDir: true,
+			wants: wants{
+				code:     http.StatusServiceUnavailable,
+				statuses: map[string]string{"database": "ok", "image_store": "fail", "default_image": "fail", "migrations": "ok"},
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			if !tt.noDefaultImg {
+				if err := os.WriteFile(filepath.Join(dir, "default.jpg"), []byte("jpeg"), 0o644); err != nil {
+					t.Fatalf("failed to write default image: %v", err)
+				}
+			}
+			if tt.missingDir {
+				dir = filepath.Join(dir, "missing")
+			}
+			store := NewLocalImageStore(dir)
+
+			ctrl := gomock.NewController(t)
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			h := &Handlers{imgStore: store, itemRepo: mockIR}
+
+			req := httptest.NewRequest("GET", "/readyz", nil)
+			rr := httptest.NewRecorder()
+			h.Readyz(rr, req)
+
+			if rr.Code != tt.code {
+				t.Errorf("expected status code %d, got %d", tt.code, rr.Code)
+			}
+			var got HealthResponse
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			statuses := map[string]string{}
+			for name, c := range got.Checks {
+				statuses[name] = c.Status
+				if c.Status == "fail" && c.Error == "" {
+					t.Errorf("expected an error message for failed check %s", name)
+				}
+			}
+			if diff := cmp.Diff(tt.statuses, statuses); diff != "" {
+				t.Errorf("unexpected check statuses (-want +got):\n%s", diff)
+			}
+			wantStatus := "ok"
+			if tt.code != http.StatusOK {
+				wantStatus = "fail"
+			}
+			if got.Status != wantStatus {
+				t.Errorf("expected status %q, got %q", wantStatus, got.Status)
+			}
+			if tt.statuses["migrations"] == "ok" {
+				if diff := cmp.Diff(applied, got.Checks["migrations"].Migrations); diff != "" {
+					t.Errorf("unexpected migrations (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyzE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "default.jpg"), []byte("jpeg"), 0o644); err != nil {
+		t.Fatalf("failed to write default image: %v", err)
+	}
+	h := &Handlers{imgStore: NewLocalImageStore(dir), itemRepo: &itemRepository{db: db}}
+
+	rr := httptest.NewRecorder()
+	h.Readyz(rr, httptest.NewRequest("GET", "/readyz", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	// the server is no longer ready once the database is closed
+	db.Close()
+	rr = httptest.NewRecorder()
+	h.Readyz(rr, httptest.NewRequest("GET", "/readyz", nil))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status code %d, got %d: %s", http.StatusServiceUnavailable, rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// imageFormat is an image format accepted for upload.
+type imageFormat struct {
+	// ContentType is the media type the image is served with.
+	ContentType string
+	// Ext is the extension of stored files, including the dot.
+	Ext string
+}
+
+// imageFormats are the accepted image formats.
+var imageFormats = []imageFormat{
+	{ContentType: "image/jpeg", Ext: ".jpg"},
+	{ContentType: "image/png", Ext: ".png"},
+	{ContentType: "image/webp", Ext: ".webp"},
+	{ContentType: "image/gif", Ext: ".gif"},
+}
+
+// errUnsupportedImage is returned for uploads that are not an accepted image format.
+var errUnsupportedImage = errors.New("image must be a JPEG, PNG, WebP or GIF")
+
+// detectImageFormat detects the format of an image from its content.
+// The file name sent by the client is not trusted.
+func detectImageFormat(image []byte) (imageFormat, error) {
+	contentType := http.DetectContentType(image)
+	for _, f := range imageFormats {
+		if f.ContentType == contentType {
+			return f, nil
+		}
+	}
+	return imageFormat{}, fmt.Errorf("%w: detected %s", errUnsupportedImage, contentType)
+}
+
+// imageFormatByExt returns the format of a stored image file from its extension.
+// .jpeg is accepted for JPEG files stored by hand, such as the default image.
+func imageFormatByExt(name string) (imageFormat, bool) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".jpeg" {
+		ext = ".jpg"
+	}
+	for _, f := range imageFormats {
+		if f.Ext == ext {
+			return f, true
+		}
+	}
+	return imageFormat{}, false
+}
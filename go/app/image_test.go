@@ -0,0 +1,177 @@
+package app
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestImage encodes a small image of the given content type.
+func newTestImage(t *testing.T, contentType string) []byte {
+	t.Helper()
+
+	return newSizedTestImage(t, contentType, 4, 4)
+}
+
+// newSizedTestImage encodes an image of the given content type and size.
+// WebP images are always 1x1.
+func newSizedTestImage(t *testing.T, contentType string, width, height int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(1, 1, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "image/png":
+		err = png.Encode(&buf, img)
+	case "image/gif":
+		err = gif.Encode(&buf, img, nil)
+	case "image/webp":
+		// the standard library has no WebP encoder, but sniffing only reads the RIFF header
+		buf.WriteString("RIFF\x1a\x00\x00\x00WEBPVP8L\x0d\x00\x00\x00\x2f\x03\xc0\x00\x00\x07\x10\x11\x11\x88\x88\xfe\x07\x00")
+	default:
+		t.Fatalf("unknown content type %s", contentType)
+	}
+	if err != nil {
+		t.Fatalf("failed to encode image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectImageFormat(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		contentType string
+		data        []byte
+		wantExt     string
+		err         bool
+	}{
+		"ok: jpeg":       {contentType: "image/jpeg", wantExt: ".jpg"},
+		"ok: png":        {contentType: "image/png", wantExt: ".png"},
+		"ok: gif":        {contentType: "image/gif", wantExt: ".gif"},
+		"ok: webp":       {contentType: "image/webp", wantExt: ".webp"},
+		"ng: text":       {data: []byte("../images/dummy.jpg"), err: true},
+		"ng: svg":        {data: []byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`), err: true},
+		"ng: html":       {data: []byte("<html><body>hello</body></html>"), err: true},
+		"ng: bmp header": {data: []byte("BM\x00\x00\x00\x00\x00\x00\x00\x00"), err: true},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			data := tt.data
+			if tt.contentType != "" {
+				data = newTestImage(t, tt.contentType)
+			}
+			got, err := detectImageFormat(data)
+			if err != nil {
+				if !tt.err {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if tt.err {
+				t.Fatalf("expected an error, got %+v", got)
+			}
+			if got.Ext != tt.wantExt || got.ContentType != tt.contentType {
+				t.Errorf("expected %s (%s), got %s (%s)", tt.contentType, tt.wantExt, got.ContentType, got.Ext)
+			}
+		})
+	}
+}
+
+func TestStoreAndServeImageFormats(t *testing.T) {
+	t.Parallel()
+
+	h := &Handlers{imgStore: NewMemoryImageStore()}
+
+	for _, contentType := range []string{"image/jpeg", "image/png", "image/gif", "image/webp"} {
+		t.Run(contentType, func(t *testing.T) {
+			t.Parallel()
+
+			data := newTestImage(t, contentType)
+			path := storeTestImage(t, h, data)
+			format, _ := detectImageFormat(data)
+			if !strings.HasSuffix(path, format.Ext) {
+				t.Errorf("expected the stored file %s to end with %s", path, format.Ext)
+			}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /images/{filename}", h.GetImage)
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest("GET", "/images/"+filepath.Base(path), nil))
+
+			if res.Code != http.StatusOK {
+				t.Fatalf("expected status code %d, got %d", http.StatusOK, res.Code)
+			}
+			if got := res.Header().Get("Content-Type"); got != contentType {
+				t.Errorf("expected content type %s, got %s", contentType, got)
+			}
+			if !bytes.Equal(res.Body.Bytes(), data) {
+				t.Errorf("served image differs from the stored one")
+			}
+		})
+	}
+}
+
+func TestUploadImageFormats(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		fileName string
+		data     func(t *testing.T) []byte
+		err      bool
+	}{
+		"ok: png named .PNG": {
+			fileName: "screenshot.PNG",
+			data:     func(t *testing.T) []byte { return newTestImage(t, "image/png") },
+		},
+		"ok: jpeg named .jpeg": {
+			fileName: "photo.jpeg",
+			data:     func(t *testing.T) []byte { return newTestImage(t, "image/jpeg") },
+		},
+		"ok: the file name does not matter": {
+			fileName: "image.txt",
+			data:     func(t *testing.T) []byte { return newTestImage(t, "image/gif") },
+		},
+		"ng: text named .jpg": {
+			fileName: "fake.jpg",
+			data:     func(t *testing.T) []byte { return []byte("not an image") },
+			err:      true,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), tt.fileName)
+			if err := os.WriteFile(path, tt.data(t), 0o644); err != nil {
+				t.Fatalf("failed to write image: %v", err)
+			}
+			req := newAddItemRequest(t, map[string]string{"name": "jacket", "category": "fashion", "image": path, "price": "1500"})
+
+			got, err := parseAddItemRequest(req, t.TempDir())
+			if (err != nil) != tt.err {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if err == nil {
+				got.Image.discard()
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// validateImageName checks that name is a flat file name,
+// so that it cannot refer to a file outside of the store.
+func validateImageName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid image name: %q", name)
+	}
+	return nil
+}
+
+// imageContentType returns the content type of an image from its name.
+func imageContentType(name string) string {
+	if f, ok := imageFormatByExt(name); ok {
+		return f.ContentType
+	}
+	return "application/octet-stream"
+}
+
+// localImageStore is an implementation of ImageStore storing images as files in a directory.
+type localImageStore struct {
+	dir string
+}
+
+// NewLocalImageStore creates an ImageStore storing images in dir.
+func NewLocalImageStore(dir string) ImageStore {
+	return &localImageStore{dir: dir}
+}
+
+func (l *localImageStore) path(name string) (string, error) {
+	if err := validateImageName(name); err != nil {
+		return "", err
+	}
+	return filepath.Join(l.dir, name), nil
+}
+
+// Put writes the image to a temporary file and renames it,
+// so that a partially written image is never visible.
+func (l *localImageStore) Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) error {
+	path, err := l.path(name)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(l.dir, ".put-*")
+	if err != nil {
+		return fmt.Errorf("failed to create image: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, r)
+	if err == nil && n != size {
+		err = fmt.Errorf("expected %d bytes, got %d", size, n)
+	}
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write image: %w", err)
+	}
+	return nil
+}
+
+func (l *localImageStore) Get(ctx context.Context, name string) (io.ReadCloser, *ImageInfo, error) {
+	path, err := l.path(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, localImageError(err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("failed to stat image: %w", err)
+	}
+	return f, localImageInfo(name, info), nil
+}
+
+func (l *localImageStore) Exists(ctx context.Context, name string) (bool, error) {
+	_, err := l.Stat(ctx, name)
+	if errors.Is(err, errImageNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (l *localImageStore) Delete(ctx context.Context, name string) error {
+	path, err := l.path(name)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete image: %w", err)
+	}
+	return nil
+}
+
+func (l *localImageStore) Stat(ctx context.Context, name string) (*ImageInfo, error) {
+	path, err := l.path(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, localImageError(err)
+	}
+	return localImageInfo(name, info), nil
+}
+
+// localImageError converts a file system error to errImageNotFound when the file does not exist.
+func localImageError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return errImageNotFound
+	}
+	return fmt.Errorf("failed to open image: %w", err)
+}
+
+func localImageInfo(name string, info fs.FileInfo) *ImageInfo {
+	return &ImageInfo{
+		Name:        name,
+		Size:        info.Size(),
+		ContentType: imageContentType(name),
+		ModTime:     info.ModTime(),
+	}
+}
+
+// memoryImageStore is an implementation of ImageStore keeping images in memory.
+// It is meant for tests.
+type memoryImageStore struct {
+	mu     sync.RWMutex
+	images map[string]memoryImage
+}
+
+type memoryImage struct {
+	data []byte
+	info ImageInfo
+}
+
+// NewMemoryImageStore creates an empty ImageStore keeping images in memory.
+func NewMemoryImageStore() ImageStore {
+	return &memoryImageStore{images: map[string]memoryImage{}}
+}
+
+func (m *memoryImageStore) Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) error {
+	if err := validateImageName(name); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+	if int64(len(data)) != size {
+		return fmt.Errorf("failed to write image: expected %d bytes, got %d", size, len(data))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.images[name] = memoryImage{
+		data: data,
+		info: ImageInfo{Name: name, Size: size, ContentType: contentType, ModTime: time.Now()},
+	}
+	return nil
+}
+
+// readSeekNopCloser is a bytes.Reader with a no-op Close.
+type readSeekNopCloser struct {
+	*bytes.Reader
+}
+
+func (readSeekNopCloser) Close() error { return nil }
+
+func (m *memoryImageStore) Get(ctx context.Context, name string) (io.ReadCloser, *ImageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	img, ok := m.images[name]
+	if !ok {
+		return nil, nil, errImageNotFound
+	}
+	info := img.info
+	return readSeekNopCloser{bytes.NewReader(img.data)}, &info, nil
+}
+
+func (m *memoryImageStore) Exists(ctx context.Context, name string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.images[name]
+	return ok, nil
+}
+
+func (m *memoryImageStore) Delete(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.images, name)
+	return nil
+}
+
+func (m *memoryImageStore) Stat(ctx context.Context, name string) (*ImageInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	img, ok := m.images[name]
+	if !ok {
+		return nil, errImageNotFound
+	}
+	info := img.info
+	return &info, nil
+}
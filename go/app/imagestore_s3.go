@@ -0,0 +1,130 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config configures an S3-compatible image store such as AWS S3 or MinIO.
+type S3Config struct {
+	// Endpoint is the host and optional port of the service, e.g. localhost:9000.
+	Endpoint  string
+	Bucket    string
+	Region    string
+	AccessKey string
+	SecretKey string
+	// UseSSL connects to the endpoint with HTTPS.
+	UseSSL bool
+}
+
+// s3ImageStore is an implementation of ImageStore storing images as objects in an S3 bucket.
+type s3ImageStore struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3ImageStore connects to an S3-compatible service and creates an ImageStore
+// storing images in the configured bucket. The bucket is created if it does not exist.
+func NewS3ImageStore(ctx context.Context, cfg S3Config) (ImageStore, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check S3 bucket: %w", err)
+	}
+	if !exists {
+		err = client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create S3 bucket: %w", err)
+		}
+	}
+	return &s3ImageStore{client: client, bucket: cfg.Bucket}, nil
+}
+
+func (s *s3ImageStore) Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) error {
+	if err := validateImageName(name); err != nil {
+		return err
+	}
+	_, err := s.client.PutObject(ctx, s.bucket, name, r, size, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to put image: %w", err)
+	}
+	return nil
+}
+
+func (s *s3ImageStore) Get(ctx context.Context, name string) (io.ReadCloser, *ImageInfo, error) {
+	if err := validateImageName(name); err != nil {
+		return nil, nil, err
+	}
+	obj, err := s.client.GetObject(ctx, s.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, s3ImageError(err)
+	}
+	// the request is sent lazily, so a missing object is only reported by Stat or Read
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, nil, s3ImageError(err)
+	}
+	return obj, s3ImageInfo(info), nil
+}
+
+func (s *s3ImageStore) Exists(ctx context.Context, name string) (bool, error) {
+	_, err := s.Stat(ctx, name)
+	if errors.Is(err, errImageNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *s3ImageStore) Delete(ctx context.Context, name string) error {
+	if err := validateImageName(name); err != nil {
+		return err
+	}
+	// S3 does not report deleting a missing object as an error
+	err := s.client.RemoveObject(ctx, s.bucket, name, minio.RemoveObjectOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to delete image: %w", err)
+	}
+	return nil
+}
+
+func (s *s3ImageStore) Stat(ctx context.Context, name string) (*ImageInfo, error) {
+	if err := validateImageName(name); err != nil {
+		return nil, err
+	}
+	info, err := s.client.StatObject(ctx, s.bucket, name, minio.StatObjectOptions{})
+	if err != nil {
+		return nil, s3ImageError(err)
+	}
+	return s3ImageInfo(info), nil
+}
+
+// s3ImageError converts an S3 error to errImageNotFound when the object does not exist.
+func s3ImageError(err error) error {
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return errImageNotFound
+	}
+	return fmt.Errorf("failed to get image: %w", err)
+}
+
+func s3ImageInfo(info minio.ObjectInfo) *ImageInfo {
+	return &ImageInfo{
+		Name:        info.Key,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ModTime:     info.LastModified,
+	}
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestValidateImageName(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		name string
+		err  bool
+	}{
+		"ok: file name":       {name: "abc.jpg"},
+		"ng: empty":           {name: "", err: true},
+		"ng: parent":          {name: "..", err: true},
+		"ng: slash":           {name: "../abc.jpg", err: true},
+		"ng: backslash":       {name: `..\abc.jpg`, err: true},
+		"ng: nested path":     {name: "images/abc.jpg", err: true},
+		"ng: current":         {name: ".", err: true},
+		"ok: dot in the name": {name: "abc_w400.jpg"},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := validateImageName(tt.name)
+			if (err != nil) != tt.err {
+				t.Errorf("validateImageName(%q) = %v, want error %v", tt.name, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestImageStore(t *testing.T) {
+	t.Parallel()
+
+	stores := map[string]func(t *testing.T) ImageStore{
+		"local": func(t *testing.T) ImageStore {
+			return NewLocalImageStore(t.TempDir())
+		},
+		"memory": func(t *testing.T) ImageStore {
+			return NewMemoryImageStore()
+		},
+		"s3": newTestS3ImageStore,
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			testImageStore(t, newStore(t))
+		})
+	}
+}
+
+// newTestS3ImageStore connects to the S3-compatible service configured by the
+// MERCARI_TEST_S3_* environment variables, e.g. a local MinIO,
+// and skips the test when they are not set.
+func newTestS3ImageStore(t *testing.T) ImageStore {
+	t.Helper()
+
+	cfg := S3Config{
+		Endpoint:  os.Getenv("MERCARI_TEST_S3_ENDPOINT"),
+		Bucket:    os.Getenv("MERCARI_TEST_S3_BUCKET"),
+		AccessKey: os.Getenv("MERCARI_TEST_S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("MERCARI_TEST_S3_SECRET_KEY"),
+	}
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		t.Skip("MERCARI_TEST_S3_ENDPOINT and MERCARI_TEST_S3_BUCKET are not set")
+	}
+	store, err := NewS3ImageStore(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("failed to create S3 image store: %v", err)
+	}
+	return store
+}
+
+// testImageStore checks the behavior every ImageStore implementation must have.
+func testImageStore(t *testing.T, store ImageStore) {
+	t.Helper()
+
+	ctx := context.Background()
+	name := "conformance.png"
+	data := newTestImage(t, "image/png")
+	t.Cleanup(func() { store.Delete(context.Background(), name) })
+
+	// a missing image
+	if ok, err := store.Exists(ctx, name); err != nil || ok {
+		t.Fatalf("Exists() = %v, %v, want false", ok, err)
+	}
+	if _, err := store.Stat(ctx, name); !errors.Is(err, errImageNotFound) {
+		t.Fatalf("Stat() error = %v, want errImageNotFound", err)
+	}
+	if _, _, err := store.Get(ctx, name); !errors.Is(err, errImageNotFound) {
+		t.Fatalf("Get() error = %v, want errImageNotFound", err)
+	}
+
+	// a stored image
+	if err := store.Put(ctx, name, bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+		t.Fatalf("failed to put image: %v", err)
+	}
+	if ok, err := store.Exists(ctx, name); err != nil || !ok {
+		t.Fatalf("Exists() = %v, %v, want true", ok, err)
+	}
+	info, err := store.Stat(ctx, name)
+	if err != nil {
+		t.Fatalf("failed to stat image: %v", err)
+	}
+	if info.Name != name || info.Size != int64(len(data)) || info.ContentType != "image/png" {
+		t.Errorf("unexpected info: %+v", info)
+	}
+	rc, info, err := store.Get(ctx, name)
+	if err != nil {
+		t.Fatalf("failed to get image: %v", err)
+	}
+	got, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		t.Fatalf("failed to read image: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("expected the stored content to be returned")
+	}
+	if info.Size != int64(len(data)) {
+		t.Errorf("expected size %d, got %d", len(data), info.Size)
+	}
+
+	// a short read is rejected and does not replace the image
+	if err := store.Put(ctx, name, bytes.NewReader(data[:1]), int64(len(data)), "image/png"); err == nil {
+		t.Errorf("expected an error for a short read")
+	}
+	if info, err := store.Stat(ctx, name); err != nil || info.Size != int64(len(data)) {
+		t.Errorf("expected the image to be kept, got %+v, %v", info, err)
+	}
+
+	// names escaping the store are rejected
+	if err := store.Put(ctx, "../"+name, bytes.NewReader(data), int64(len(data)), "image/png"); err == nil {
+		t.Errorf("expected an error for an invalid name")
+	}
+
+	// a deleted image, deleting twice is not an error
+	for range 2 {
+		if err := store.Delete(ctx, name); err != nil {
+			t.Fatalf("failed to delete image: %v", err)
+		}
+	}
+	if ok, err := store.Exists(ctx, name); err != nil || ok {
+		t.Errorf("Exists() after Delete() = %v, %v, want false", ok, err)
+	}
+}
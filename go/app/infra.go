@@ -4,21 +4,143 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
 
 	// STEP 5-1: uncomment this line
 	"database/sql"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"mercari-build-training/db/migrations"
 )
 
-var errImageNotFound = errors.New("image not found")
+var (
+	errImageNotFound    = errors.New("image not found")
+	errItemNotFound     = errors.New("item not found")
+	errCategoryNotFound = errors.New("category not found")
+	errCategoryExists   = errors.New("category already exists")
+	errUserNotFound     = errors.New("user not found")
+	errUserExists       = errors.New("user already exists")
+	errSessionNotFound  = errors.New("session not found")
+	errStatusConflict   = errors.New("item status changed")
+	errItemNotOnSale    = errors.New("item is not on sale")
+	errOwnItem          = errors.New("cannot buy own item")
+)
 
+// Item is an item on sale. SellerID is 0 for items listed before users existed.
+// Price is in the minor units of Currency, e.g. cents for USD and yen for JPY.
 type Item struct {
-	ID   			int    `db:"id" json:"id"`
-	Name 			string `db:"name" json:"name"`
-	Category 	string `db:"category" json:"category"`
-	ImageName string `db:"image_name" json:"image_name"`
+	ID        int        `db:"id" json:"id"`
+	Name      string     `db:"name" json:"name"`
+	Category  string     `db:"category" json:"category"`
+	ImageName string     `db:"image_name" json:"image_name"`
+	SellerID  int        `db:"seller_id" json:"seller_id,omitempty"`
+	Price     int64      `db:"price" json:"price"`
+	Currency  string     `db:"currency" json:"currency"`
+	Status    ItemStatus `db:"status" json:"status"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+}
+
+// User is an account that can log in.
+type User struct {
+	ID   int    `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+	// PasswordHash is the bcrypt hash of the password. It is never sent to clients.
+	PasswordHash string    `db:"password_hash" json:"-"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// Order is a purchase of an item.
+// Price and Currency are those of the item when it was bought.
+type Order struct {
+	ID        int       `db:"id" json:"id"`
+	ItemID    int       `db:"item_id" json:"item_id"`
+	BuyerID   int       `db:"buyer_id" json:"buyer_id"`
+	SellerID  int       `db:"seller_id" json:"seller_id"`
+	Price     int64     `db:"price" json:"price"`
+	Currency  string    `db:"currency" json:"currency"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// OrderRole is the side of the orders a user lists.
+type OrderRole string
+
+const (
+	RoleBuyer  OrderRole = "buyer"
+	RoleSeller OrderRole = "seller"
+)
+
+// Image is a stored image file shared by the items that use it.
+type Image struct {
+	ID int `db:"id" json:"id"`
+	// Name is the file name in the image store, <sha256>.<ext>.
+	Name        string `db:"name" json:"name"`
+	Hash        string `db:"hash" json:"hash"`
+	Size        int64  `db:"size" json:"size"`
+	ContentType string `db:"content_type" json:"content_type"`
+	// RefCount is the number of items, including soft-deleted ones, using the image.
+	RefCount  int       `db:"ref_count" json:"ref_count"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	// UnreferencedAt is when RefCount last dropped to 0, or nil while the image is used.
+	UnreferencedAt *time.Time `db:"unreferenced_at" json:"unreferenced_at,omitempty"`
+}
+
+type Category struct {
+	ID   int    `db:"id" json:"id"`
+	Name string `db:"name" json:"name"`
+	// ItemCount is the number of items in the category, excluding deleted ones.
+	ItemCount int `db:"item_count" json:"item_count"`
+}
+
+// ItemSortKey is a key items can be sorted by.
+type ItemSortKey string
+
+const (
+	SortByID        ItemSortKey = "id"
+	SortByCreatedAt ItemSortKey = "created_at"
+)
+
+// SortOrder is the direction of a sort.
+type SortOrder string
+
+const (
+	OrderAsc  SortOrder = "asc"
+	OrderDesc SortOrder = "desc"
+)
+
+// ItemQuery specifies which items to list, in which order, and which page.
+type ItemQuery struct {
+	// CategoryID filters items by category id when it is not 0.
+	CategoryID int
+	// Category filters items by category name when it is not empty.
+	Category string
+	// NamePrefix filters items whose name starts with it when it is not empty.
+	NamePrefix string
+	// SellerID filters items by seller when it is not 0.
+	SellerID int
+	// Currency filters items by currency when it is not empty.
+	Currency string
+	// MinPrice and MaxPrice filter items by price in minor units when they are not nil.
+	// They filter items in the default currency if Currency is empty.
+	MinPrice *int64
+	MaxPrice *int64
+	// Status filters items by status when it is not empty.
+	Status ItemStatus
+	// IncludeDeleted includes soft-deleted items.
+	IncludeDeleted bool
+	// Sort is the key to sort by. Items with the same key are ordered by id.
+	Sort ItemSortKey
+	// Order is the direction to sort in.
+	Order SortOrder
+	// After is the last item on the previous page, or nil for the first page.
+	After *Item
+	// Limit is the maximum number of items on the page.
+	Limit int
 }
 
 // Please run `go generate ./...` to generate the mock implementation
@@ -27,99 +149,1130 @@ type Item struct {
 //go:generate go run go.uber.org/mock/mockgen -source=$GOFILE -package=${GOPACKAGE} -destination=./mock_$GOFILE
 type ItemRepository interface {
 	Insert(ctx context.Context, item *Item) error
-	GetItems() ([]Item, error)
+	Update(ctx context.Context, item *Item) error
+	Delete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) error
+	GetByID(ctx context.Context, id int) (*Item, error)
+	// UpdateStatus changes the status of the item with the given id from from to to.
+	// It returns errStatusConflict if the status is no longer from.
+	UpdateStatus(ctx context.Context, id int, from, to ItemStatus) error
+	// Purchase sells the item with the given id to the buyer and returns the order.
+	// It returns errItemNotFound, errOwnItem or errItemNotOnSale if the item cannot be bought.
+	Purchase(ctx context.Context, itemID, buyerID int) (*Order, error)
+	// ListOrders returns the orders of the user as the buyer or the seller, newest first.
+	ListOrders(ctx context.Context, userID int, role OrderRole) ([]Order, error)
+	// GetSellerID returns the seller of the item with the given id, including soft-deleted items.
+	// It returns 0 for items without a seller and errItemNotFound if there is no such item.
+	GetSellerID(ctx context.Context, id int) (int, error)
+	ListItems(ctx context.Context, q ItemQuery) (items []Item, hasNext bool, err error)
+	Search(ctx context.Context, query string) ([]Item, error)
+	ListCategories(ctx context.Context) ([]Category, error)
+	GetCategoryByName(ctx context.Context, name string) (*Category, error)
+	CreateCategory(ctx context.Context, name string) (*Category, error)
+	RenameCategory(ctx context.Context, id int, name string) (*Category, error)
+	MergeCategories(ctx context.Context, srcID, dstID int) (*Category, error)
+	// SaveImage records an image so that items can refer to it by name.
+	// It reports whether the record is new, in which case the file must be stored even if it exists.
+	SaveImage(ctx context.Context, img *Image) (created bool, err error)
+	// ListOrphanImages returns the images no item has used since before.
+	ListOrphanImages(ctx context.Context, before time.Time) ([]Image, error)
+	// DeleteOrphanImage deletes the record of an image no item has used since before,
+	// and its files with deleteFiles while no one can save the image again.
+	// It reports false if the image is used again or does not exist.
+	DeleteOrphanImage(ctx context.Context, name string, before time.Time, deleteFiles func(context.Context) error) (bool, error)
+	// Ping checks that the database is reachable.
+	Ping(ctx context.Context) error
+	// MigrationStatus returns every schema migration and when it was applied.
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
+	// Close closes the database connection.
+	Close() error
+}
+
+// UserRepository is an interface to manage users and their sessions.
+// Sessions are identified by the hash of their token, so the tokens themselves are never stored.
+type UserRepository interface {
+	// CreateUser creates a user. It returns errUserExists if the name is taken, ignoring case.
+	CreateUser(ctx context.Context, name, passwordHash string) (*User, error)
+	// GetUserByName returns the user with the given name, ignoring case.
+	// It returns errUserNotFound if there is no such user.
+	GetUserByName(ctx context.Context, name string) (*User, error)
+	// GetUserByID returns the user with the given id.
+	// It returns errUserNotFound if there is no such user.
+	GetUserByID(ctx context.Context, id int) (*User, error)
+	// CreateSession creates a session of the user that expires at expiresAt.
+	CreateSession(ctx context.Context, tokenHash string, userID int, expiresAt time.Time) error
+	// GetSessionUser returns the user of the session that has not expired at now.
+	// It returns errSessionNotFound if there is no such session.
+	GetSessionUser(ctx context.Context, tokenHash string, now time.Time) (*User, error)
+	// DeleteSession deletes the session. Deleting a session that does not exist is not an error.
+	DeleteSession(ctx context.Context, tokenHash string) error
+	// Close closes the database connection.
+	Close() error
+}
+
+// ImageInfo describes a stored image.
+type ImageInfo struct {
+	Name        string
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// ImageStore is an interface to store image files.
+// Images are identified by flat names such as <sha256>.jpg.
+// Methods return errImageNotFound for images that do not exist.
+type ImageStore interface {
+	// Put stores size bytes read from r as the image name, replacing any existing one.
+	Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) error
+	// Get opens the image name. The caller must close it.
+	// The reader also implements io.Seeker if the store supports random access.
+	Get(ctx context.Context, name string) (io.ReadCloser, *ImageInfo, error)
+	// Exists reports whether the image name exists.
+	Exists(ctx context.Context, name string) (bool, error)
+	// Delete deletes the image name. Deleting an image that does not exist is not an error.
+	Delete(ctx context.Context, name string) error
+	// Stat returns information about the image name.
+	Stat(ctx context.Context, name string) (*ImageInfo, error)
 }
 
 // itemRepository is an implementation of ItemRepository
 type itemRepository struct {
 	// db is a database connection
 	db *sql.DB
+	// fts reports whether the items_fts full-text index is available
+	fts bool
+	// metrics records the duration of each operation
+	metrics *Metrics
 }
 
 // NewItemRepository connects db and creates a new itemRepository.
-func NewItemRepository(dbPath string) (ItemRepository, error) {
+// Pending migrations are applied before the repository is returned.
+// metrics may be nil.
+func NewItemRepository(dbPath string, metrics *Metrics) (ItemRepository, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to the database: %w", err)
 	}
-	// if the table does not exist, read the schema
-	schemaBytes, err := os.ReadFile("db/items.sql")
+	// bring the schema up to date
+	fts, err := setupSchema(db)
+	if err != nil {
+		return nil, err
+	}
+	return &itemRepository{db: db, fts: fts, metrics: metrics}, nil
+}
+
+// setupSchema applies pending migrations.
+// When SQLite is built with FTS5, it also creates the search index
+// and reports that the index is available.
+func setupSchema(db *sql.DB) (fts bool, err error) {
+	_, err = Migrate(db)
+	if err != nil {
+		return false, err
+	}
+
+	fts, err = hasFTS5(db)
+	if err != nil {
+		return false, err
+	}
+	triggers, err := searchIndexTriggers(db)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read schema: %w", err)
+		return false, err
+	}
+	if !fts {
+		// the triggers of a search index created by a build with FTS5 would make every write to items fail
+		for _, name := range triggers {
+			if _, err := db.Exec("DROP TRIGGER " + name); err != nil {
+				return false, fmt.Errorf("failed to drop search index trigger %s: %w", name, err)
+			}
+		}
+		if len(triggers) > 0 {
+			slog.Warn("dropped the search index triggers created by a build with FTS5, so that items can be written. "+
+				"The index is rebuilt when a build with FTS5 starts", "triggers", len(triggers))
+		}
+		slog.Warn("SQLite is built without FTS5: GET /search falls back to a LIKE scan ordered by id instead of relevance. " +
+			"Build with -tags sqlite_fts5 to use the search index")
+		return false, nil
 	}
 
-	// create the table
-	_, err = db.Exec(string(schemaBytes))
+	// a build without FTS5 dropped the triggers, so the index may be out of date: rebuild it
+	exists, err := tableExists(db, "items_fts")
+	if err != nil {
+		return false, err
+	}
+	if exists && len(triggers) == 0 {
+		if _, err := db.Exec("DELETE FROM items_fts"); err != nil {
+			return false, fmt.Errorf("failed to clear search index: %w", err)
+		}
+	}
+	_, err = db.Exec(migrations.SearchIndex)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create table: %w", err)
+		return false, fmt.Errorf("failed to create search index: %w", err)
 	}
-	return &itemRepository{db: db}, nil
+	return true, nil
 }
 
-// Insert inserts an item into the repository.
+// searchIndexTriggers returns the names of the triggers keeping the search index in sync.
+func searchIndexTriggers(db *sql.DB) ([]string, error) {
+	rows, err := db.Query(`SELECT name FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'items\_fts\_%' ESCAPE '\'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list search index triggers: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return names, nil
+}
+
+// tableExists reports whether the table name exists.
+func tableExists(db *sql.DB, name string) (bool, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to check table %s: %w", name, err)
+	}
+	return n > 0, nil
+}
+
+// Ping checks that the database is reachable.
+func (i *itemRepository) Ping(ctx context.Context) error {
+	return i.db.PingContext(ctx)
+}
+
+// MigrationStatus returns every schema migration and when it was applied.
+func (i *itemRepository) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	return GetMigrationStatus(i.db)
+}
+
+// Close closes the database connection.
+func (i *itemRepository) Close() error {
+	return i.db.Close()
+}
+
+// Insert inserts an item into the repository. New items are on sale.
+// The item is linked to the image saved by SaveImage with the same name, if any.
+// An item with SellerID 0 is inserted without a seller.
+// item.Category is set to the name the category is stored under, which may differ in case.
 func (i *itemRepository) Insert(ctx context.Context, item *Item) error {
-	// 
+	defer i.metrics.observeQuery("insert", time.Now())
+
+	categoryID, category, err := i.getOrCreateCategory(ctx, item.Category)
+	if err != nil {
+		return err
+	}
+	item.Category = category
+
+	// insert an item using the category ID
+	_, err = i.db.Exec(
+		"INSERT INTO items (name, category_id, image_name, image_id, seller_id, price, currency) VALUES (?, ?, ?, "+imageIDByName+", NULLIF(?, 0), ?, ?)",
+		item.Name, categoryID, item.ImageName, path.Base(item.ImageName), item.SellerID, item.Price, item.Currency,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert an item: %w", err)
+	}
+	return nil
+}
+
+// Update updates the name, category, image and price of the item with item.ID.
+// The seller of an item never changes, and its status is changed by UpdateStatus.
+// item.Category is set to the name the category is stored under, which may differ in case.
+// It returns errItemNotFound if there is no such item or it is deleted.
+func (i *itemRepository) Update(ctx context.Context, item *Item) error {
+	defer i.metrics.observeQuery("update", time.Now())
+
+	categoryID, category, err := i.getOrCreateCategory(ctx, item.Category)
+	if err != nil {
+		return err
+	}
+	item.Category = category
+
+	result, err := i.db.ExecContext(ctx,
+		"UPDATE items SET name = ?, category_id = ?, image_name = ?, image_id = "+imageIDByName+", price = ?, currency = ? WHERE id = ? AND deleted_at IS NULL",
+		item.Name, categoryID, item.ImageName, path.Base(item.ImageName), item.Price, item.Currency, item.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update item %d: %w", item.ID, err)
+	}
+	return checkItemAffected(result)
+}
+
+// Delete soft-deletes the item with the given id.
+// It returns errItemNotFound if there is no such item or it is already deleted.
+func (i *itemRepository) Delete(ctx context.Context, id int) error {
+	defer i.metrics.observeQuery("delete", time.Now())
+
+	result, err := i.db.ExecContext(ctx,
+		"UPDATE items SET deleted_at = CURRENT_TIMESTAMP WHERE id = ? AND deleted_at IS NULL",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete item %d: %w", id, err)
+	}
+	return checkItemAffected(result)
+}
+
+// Restore restores the soft-deleted item with the given id.
+// It returns errItemNotFound if there is no such deleted item.
+func (i *itemRepository) Restore(ctx context.Context, id int) error {
+	defer i.metrics.observeQuery("restore", time.Now())
+
+	result, err := i.db.ExecContext(ctx,
+		"UPDATE items SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL",
+		id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore item %d: %w", id, err)
+	}
+	return checkItemAffected(result)
+}
+
+// UpdateStatus changes the status of the item with the given id from from to to.
+// The status is only changed if it is still from, so that concurrent transitions cannot both succeed.
+// It returns errItemNotFound if there is no such item or it is deleted,
+// and errStatusConflict if its status is no longer from.
+func (i *itemRepository) UpdateStatus(ctx context.Context, id int, from, to ItemStatus) error {
+	defer i.metrics.observeQuery("update_status", time.Now())
+
+	result, err := i.db.ExecContext(ctx,
+		"UPDATE items SET status = ? WHERE id = ? AND status = ? AND deleted_at IS NULL",
+		to, id, from,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update status of item %d: %w", id, err)
+	}
+	err = checkItemAffected(result)
+	if !errors.Is(err, errItemNotFound) {
+		return err
+	}
+	// tell a missing item from one whose status has changed
+	if _, err := i.GetByID(ctx, id); err != nil {
+		return err
+	}
+	return errStatusConflict
+}
+
+// orderColumns are the columns scanned into an Order.
+const orderColumns = "id, item_id, buyer_id, seller_id, price, currency, created_at"
+
+// Purchase sells the item with the given id to the buyer and returns the order.
+// In one transaction, the item is marked sold if it is on sale and not the buyer's own,
+// and an order is created at its current price.
+// Marking the item sold is the first statement, so the transaction takes the write lock
+// before reading anything: a concurrent purchase waits for the lock and then finds the item sold.
+// It returns errItemNotFound if there is no such item or it is deleted,
+// errOwnItem if the buyer sells it, and errItemNotOnSale if it is not on sale.
+func (i *itemRepository) Purchase(ctx context.Context, itemID, buyerID int) (*Order, error) {
+	defer i.metrics.observeQuery("purchase", time.Now())
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// items without a seller cannot be bought, since nobody would receive the order
+	result, err := tx.ExecContext(ctx, `
+		UPDATE items SET status = ?
+		WHERE id = ? AND status = ? AND deleted_at IS NULL AND seller_id IS NOT NULL AND seller_id != ?
+	`, StatusSold, itemID, StatusOnSale, buyerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark item %d sold: %w", itemID, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if n == 0 {
+		return nil, purchaseError(ctx, tx, itemID, buyerID)
+	}
+
+	var order Order
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO orders (item_id, buyer_id, seller_id, price, currency)
+		SELECT id, ?, seller_id, price, currency FROM items WHERE id = ?
+		RETURNING `+orderColumns,
+		buyerID, itemID,
+	).Scan(&order.ID, &order.ItemID, &order.BuyerID, &order.SellerID, &order.Price, &order.Currency, &order.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &order, nil
+}
+
+// purchaseError returns why the buyer could not buy the item with the given id.
+func purchaseError(ctx context.Context, tx *sql.Tx, itemID, buyerID int) error {
+	var sellerID int
+	var status ItemStatus
+	err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(seller_id, 0), status FROM items WHERE id = ? AND deleted_at IS NULL", itemID,
+	).Scan(&sellerID, &status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errItemNotFound
+		}
+		return fmt.Errorf("failed to get item %d: %w", itemID, err)
+	}
+	if sellerID == buyerID {
+		return errOwnItem
+	}
+	return errItemNotOnSale
+}
+
+// ListOrders returns the orders of the user as the buyer or the seller, newest first.
+func (i *itemRepository) ListOrders(ctx context.Context, userID int, role OrderRole) ([]Order, error) {
+	defer i.metrics.observeQuery("list_orders", time.Now())
+
+	var column string
+	switch role {
+	case RoleBuyer:
+		column = "buyer_id"
+	case RoleSeller:
+		column = "seller_id"
+	default:
+		return nil, fmt.Errorf("unknown order role: %s", role)
+	}
+	rows, err := i.db.QueryContext(ctx,
+		"SELECT "+orderColumns+" FROM orders WHERE "+column+" = ? ORDER BY id DESC", userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []Order
+	for rows.Next() {
+		var o Order
+		if err := rows.Scan(&o.ID, &o.ItemID, &o.BuyerID, &o.SellerID, &o.Price, &o.Currency, &o.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return orders, nil
+}
+
+// imageIDByName is a subquery selecting the id of the image whose name is the parameter,
+// or NULL for images that are not saved, such as the default image.
+const imageIDByName = "(SELECT id FROM images WHERE name = ?)"
+
+// checkItemAffected returns errItemNotFound if result affected no rows.
+func checkItemAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if n == 0 {
+		return errItemNotFound
+	}
+	return nil
+}
+
+// getOrCreateCategory returns the id and the stored name of the category with the given name, ignoring case.
+// The category is created if it does not exist yet.
+func (i *itemRepository) getOrCreateCategory(ctx context.Context, name string) (int, string, error) {
 	var categoryID int
-	err := i.db.QueryRowContext(ctx, "SELECT id FROM categories WHERE name = ?", item.Category).Scan(&categoryID)
+	var stored string
+	err := i.db.QueryRowContext(ctx, "SELECT id, name FROM categories WHERE name = ? COLLATE NOCASE", name).Scan(&categoryID, &stored)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			// insert a new category
-			result, err := i.db.ExecContext(ctx, "INSERT INTO categories (name) VALUES (?)", item.Category)
+			result, err := i.db.ExecContext(ctx, "INSERT INTO categories (name) VALUES (?)", name)
 			if err != nil {
-				return fmt.Errorf("failed to insert a category: %w", err)
+				return 0, "", fmt.Errorf("failed to insert a category: %w", err)
 			}
 			newID, err := result.LastInsertId()
 			if err != nil {
-				return fmt.Errorf("failed to get new category ID: %w", err)
+				return 0, "", fmt.Errorf("failed to get new category ID: %w", err)
 			}
 			categoryID = int(newID)
+			stored = name
 		} else {
-			return fmt.Errorf("failed to query category: %w", err)
+			return 0, "", fmt.Errorf("failed to query category: %w", err)
 		}
 	}
+	return categoryID, stored, nil
+}
 
-	// insert an item using the category ID
-	_, err = i.db.Exec(
-		"INSERT INTO items (name, category_id, image_name) VALUES (?, ?, ?)",
-		item.Name, categoryID, item.ImageName,
-	)
+// GetByID returns the item with the given id.
+// It returns errItemNotFound if there is no such item or it is deleted.
+func (i *itemRepository) GetByID(ctx context.Context, id int) (*Item, error) {
+	defer i.metrics.observeQuery("get_by_id", time.Now())
+
+	var item Item
+	err := i.db.QueryRowContext(ctx, `
+		SELECT `+itemColumns+`
+		FROM items i
+		JOIN categories c ON i.category_id = c.id
+		WHERE i.id = ? AND i.deleted_at IS NULL
+	`, id).Scan(&item.ID, &item.Name, &item.Category, &item.ImageName, &item.SellerID, &item.Price, &item.Currency, &item.Status, &item.CreatedAt, &item.DeletedAt)
 	if err != nil {
-		return fmt.Errorf("failed to insert an item: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errItemNotFound
+		}
+		return nil, fmt.Errorf("failed to get item %d: %w", id, err)
 	}
-	return nil
+	return &item, nil
 }
 
-// GetItems returns all items from the repository.
-func (i *itemRepository) GetItems() ([]Item, error) {
-	rows, err := i.db.Query(`
-				SELECT i.id, i.name, c.name AS category, i.image_name 
-        FROM items i 
-        JOIN categories c ON i.category_id = c.id
-	`)
+// GetSellerID returns the seller of the item with the given id, including soft-deleted items.
+// It returns 0 for items without a seller and errItemNotFound if there is no such item.
+func (i *itemRepository) GetSellerID(ctx context.Context, id int) (int, error) {
+	defer i.metrics.observeQuery("get_seller_id", time.Now())
+
+	var sellerID int
+	err := i.db.QueryRowContext(ctx, "SELECT COALESCE(seller_id, 0) FROM items WHERE id = ?", id).Scan(&sellerID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get items: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, errItemNotFound
+		}
+		return 0, fmt.Errorf("failed to get seller of item %d: %w", id, err)
 	}
-	// defer make sure rows.Close() is called after the function returns
-	defer rows.Close()
+	return sellerID, nil
+}
+
+// itemColumns are the columns scanned by scanItems.
+// Queries must alias items as i and categories as c.
+const itemColumns = "i.id, i.name, c.name AS category, i.image_name, COALESCE(i.seller_id, 0), i.price, i.currency, i.status, i.created_at, i.deleted_at"
 
+// scanItems reads every row selected with itemColumns.
+func scanItems(rows *sql.Rows) ([]Item, error) {
 	var items []Item
 	// iterate over the rows
 	for rows.Next() {
 		var item Item
-		err:= rows.Scan(&item.ID, &item.Name, &item.Category, &item.ImageName)
+		err := rows.Scan(&item.ID, &item.Name, &item.Category, &item.ImageName, &item.SellerID, &item.Price, &item.Currency, &item.Status, &item.CreatedAt, &item.DeletedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 		items = append(items, item)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
 	return items, nil
 }
 
+// ListItems returns up to q.Limit items that match q, starting after q.After.
+// hasNext reports whether more items follow the returned page.
+func (i *itemRepository) ListItems(ctx context.Context, q ItemQuery) (items []Item, hasNext bool, err error) {
+	defer i.metrics.observeQuery("list_items", time.Now())
+
+	var sortColumn string
+	switch q.Sort {
+	case SortByID, "":
+		sortColumn = "i.id"
+	case SortByCreatedAt:
+		sortColumn = "i.created_at"
+	default:
+		return nil, false, fmt.Errorf("unknown sort key: %s", q.Sort)
+	}
+	cmpOp, direction := ">", "ASC"
+	switch q.Order {
+	case OrderAsc, "":
+	case OrderDesc:
+		cmpOp, direction = "<", "DESC"
+	default:
+		return nil, false, fmt.Errorf("unknown sort order: %s", q.Order)
+	}
+
+	conds := []string{"1 = 1"}
+	var args []any
+	if !q.IncludeDeleted {
+		conds = append(conds, "i.deleted_at IS NULL")
+	}
+	if q.CategoryID != 0 {
+		conds = append(conds, "i.category_id = ?")
+		args = append(args, q.CategoryID)
+	}
+	if q.Category != "" {
+		conds = append(conds, "c.name = ? COLLATE NOCASE")
+		args = append(args, q.Category)
+	}
+	if q.NamePrefix != "" {
+		conds = append(conds, `i.name LIKE ? ESCAPE '\'`)
+		args = append(args, escapeLike(q.NamePrefix)+"%")
+	}
+	if q.SellerID != 0 {
+		conds = append(conds, "i.seller_id = ?")
+		args = append(args, q.SellerID)
+	}
+	// minor units differ between currencies, so prices are only compared within one currency
+	currency := q.Currency
+	if currency == "" && (q.MinPrice != nil || q.MaxPrice != nil) {
+		currency = defaultCurrency
+	}
+	if currency != "" {
+		conds = append(conds, "i.currency = ?")
+		args = append(args, currency)
+	}
+	if q.MinPrice != nil {
+		conds = append(conds, "i.price >= ?")
+		args = append(args, *q.MinPrice)
+	}
+	if q.MaxPrice != nil {
+		conds = append(conds, "i.price <= ?")
+		args = append(args, *q.MaxPrice)
+	}
+	if q.Status != "" {
+		conds = append(conds, "i.status = ?")
+		args = append(args, q.Status)
+	}
+	if q.After != nil {
+		// keyset pagination: continue right after the last item of the previous page
+		switch sortColumn {
+		case "i.id":
+			conds = append(conds, "i.id "+cmpOp+" ?")
+			args = append(args, q.After.ID)
+		default:
+			after := q.After.CreatedAt.UTC().Format(time.DateTime)
+			conds = append(conds, "("+sortColumn+" "+cmpOp+" ? OR ("+sortColumn+" = ? AND i.id "+cmpOp+" ?))")
+			args = append(args, after, after, q.After.ID)
+		}
+	}
+
+	// fetch one extra row to know whether there is a next page
+	args = append(args, q.Limit+1)
+	rows, err := i.db.QueryContext(ctx, `
+		SELECT `+itemColumns+`
+		FROM items i
+		JOIN categories c ON i.category_id = c.id
+		WHERE `+strings.Join(conds, " AND ")+`
+		ORDER BY `+sortColumn+` `+direction+`, i.id `+direction+`
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list items: %w", err)
+	}
+	defer rows.Close()
+
+	items, err = scanItems(rows)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(items) > q.Limit {
+		return items[:q.Limit], true, nil
+	}
+	return items, false, nil
+}
+
+// hasFTS5 reports whether SQLite supports FTS5.
+// FTS5 is only compiled into go-sqlite3 with the sqlite_fts5 build tag.
+func hasFTS5(db *sql.DB) (bool, error) {
+	var ok bool
+	err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&ok)
+	if err != nil {
+		return false, fmt.Errorf("failed to check FTS5 support: %w", err)
+	}
+	return ok, nil
+}
+
+// Search returns items whose name or category matches every keyword in query.
+// Soft-deleted items are excluded.
+// Results are ordered by relevance when the full-text index is available.
+func (i *itemRepository) Search(ctx context.Context, query string) ([]Item, error) {
+	defer i.metrics.observeQuery("search", time.Now())
+
+	keywords := strings.Fields(query)
+	if len(keywords) == 0 {
+		return nil, nil
+	}
+
+	var rows *sql.Rows
+	var err error
+	if i.fts {
+		rows, err = i.db.QueryContext(ctx, `
+			SELECT `+itemColumns+`
+			FROM items_fts f
+			JOIN items i ON i.id = f.rowid
+			JOIN categories c ON i.category_id = c.id
+			WHERE items_fts MATCH ? AND i.deleted_at IS NULL
+			ORDER BY f.rank
+		`, buildMatchQuery(keywords))
+	} else {
+		// fall back to a LIKE scan when SQLite is built without FTS5
+		conds := make([]string, 0, len(keywords))
+		args := make([]any, 0, len(keywords)*2)
+		for _, kw := range keywords {
+			conds = append(conds, `(i.name LIKE ? ESCAPE '\' OR c.name LIKE ? ESCAPE '\')`)
+			pattern := "%" + escapeLike(kw) + "%"
+			args = append(args, pattern, pattern)
+		}
+		rows, err = i.db.QueryContext(ctx, `
+			SELECT `+itemColumns+`
+			FROM items i
+			JOIN categories c ON i.category_id = c.id
+			WHERE i.deleted_at IS NULL AND `+strings.Join(conds, " AND ")+`
+			ORDER BY i.id
+		`, args...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to search items: %w", err)
+	}
+	defer rows.Close()
+
+	return scanItems(rows)
+}
+
+// buildMatchQuery builds an FTS5 query that matches every keyword as a prefix.
+// Each keyword is quoted so that user input is never parsed as FTS5 syntax.
+func buildMatchQuery(keywords []string) string {
+	terms := make([]string, 0, len(keywords))
+	for _, kw := range keywords {
+		terms = append(terms, `"`+strings.ReplaceAll(kw, `"`, `""`)+`"*`)
+	}
+	return strings.Join(terms, " ")
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// ListCategories returns all categories with their item counts ordered by name.
+func (i *itemRepository) ListCategories(ctx context.Context) ([]Category, error) {
+	defer i.metrics.observeQuery("list_categories", time.Now())
+
+	rows, err := i.db.QueryContext(ctx, `
+		SELECT c.id, c.name, COUNT(i.id) AS item_count
+		FROM categories c
+		LEFT JOIN items i ON i.category_id = c.id AND i.deleted_at IS NULL
+		GROUP BY c.id
+		ORDER BY c.name COLLATE NOCASE, c.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		var c Category
+		if err := rows.Scan(&c.ID, &c.Name, &c.ItemCount); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		categories = append(categories, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return categories, nil
+}
+
+// GetCategoryByName returns the category with the given name, ignoring case.
+// It returns errCategoryNotFound if there is no such category.
+func (i *itemRepository) GetCategoryByName(ctx context.Context, name string) (*Category, error) {
+	defer i.metrics.observeQuery("get_category_by_name", time.Now())
 
-// StoreImage stores an image and returns an error if any.
-// This package doesn't have a related interface for simplicity.
-func StoreImage(fileName string, image []byte) error {
-	// STEP 4-4: add an implementation to store an image
+	return getCategory(ctx, i.db, "c.name = ? COLLATE NOCASE", name)
+}
+
+// CreateCategory creates a category with the given name.
+// It returns errCategoryExists if a category with the same name exists, ignoring case.
+func (i *itemRepository) CreateCategory(ctx context.Context, name string) (*Category, error) {
+	defer i.metrics.observeQuery("create_category", time.Now())
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategoryNameFree(ctx, tx, name, 0); err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, "INSERT INTO categories (name) VALUES (?)", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert a category: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get new category ID: %w", err)
+	}
 
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return &Category{ID: int(id), Name: name}, nil
+}
+
+// RenameCategory renames the category with the given id.
+// It returns errCategoryNotFound if there is no such category,
+// and errCategoryExists if another category has the same name, ignoring case.
+func (i *itemRepository) RenameCategory(ctx context.Context, id int, name string) (*Category, error) {
+	defer i.metrics.observeQuery("rename_category", time.Now())
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategoryNameFree(ctx, tx, name, id); err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, "UPDATE categories SET name = ? WHERE id = ?", name, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename category %d: %w", id, err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	} else if n == 0 {
+		return nil, errCategoryNotFound
+	}
+	category, err := getCategory(ctx, tx, "c.id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return category, nil
+}
+
+// MergeCategories moves every item of the category srcID to the category dstID
+// and deletes srcID in a single transaction. It returns the merged category.
+// It returns errCategoryNotFound if either category does not exist.
+func (i *itemRepository) MergeCategories(ctx context.Context, srcID, dstID int) (*Category, error) {
+	defer i.metrics.observeQuery("merge_categories", time.Now())
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range []int{srcID, dstID} {
+		if _, err := getCategory(ctx, tx, "c.id = ?", id); err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE items SET category_id = ? WHERE category_id = ?", dstID, srcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move items: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM categories WHERE id = ?", srcID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete category %d: %w", srcID, err)
+	}
+	category, err := getCategory(ctx, tx, "c.id = ?", dstID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return category, nil
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// getCategory returns the category matching cond with its item count.
+// It returns errCategoryNotFound if there is no such category.
+func getCategory(ctx context.Context, q queryer, cond string, args ...any) (*Category, error) {
+	var c Category
+	err := q.QueryRowContext(ctx, `
+		SELECT c.id, c.name, COUNT(i.id) AS item_count
+		FROM categories c
+		LEFT JOIN items i ON i.category_id = c.id AND i.deleted_at IS NULL
+		WHERE `+cond+`
+		GROUP BY c.id
+	`, args...).Scan(&c.ID, &c.Name, &c.ItemCount)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errCategoryNotFound
+		}
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+	return &c, nil
+}
+
+// checkCategoryNameFree returns errCategoryExists if a category other than exceptID
+// has the given name, ignoring case.
+func checkCategoryNameFree(ctx context.Context, q queryer, name string, exceptID int) error {
+	var id int
+	err := q.QueryRowContext(ctx,
+		"SELECT id FROM categories WHERE name = ? COLLATE NOCASE AND id != ?", name, exceptID,
+	).Scan(&id)
+	if err == nil {
+		return errCategoryExists
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to query category: %w", err)
+	}
+	return nil
+}
+
+// SaveImage records an image so that items can refer to it by name.
+// Saving an image that is already recorded restarts the grace period
+// of the image if no item uses it, so that it is not collected before an item refers to it.
+// It reports whether the record is new. The file of a new record must be stored even if it exists,
+// since the file may belong to a record DeleteOrphanImage has just deleted together with it.
+func (i *itemRepository) SaveImage(ctx context.Context, img *Image) (bool, error) {
+	defer i.metrics.observeQuery("save_image", time.Now())
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO images (name, hash, size, content_type, unreferenced_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (name) DO NOTHING
+	`, img.Name, img.Hash, img.Size, img.ContentType)
+	if err != nil {
+		return false, fmt.Errorf("failed to save image %s: %w", img.Name, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	created := n > 0
+	if !created {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE images SET unreferenced_at = CURRENT_TIMESTAMP WHERE name = ? AND ref_count = 0",
+			img.Name,
+		)
+		if err != nil {
+			return false, fmt.Errorf("failed to save image %s: %w", img.Name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return created, nil
+}
+
+// ListOrphanImages returns the images no item has used since before, oldest first.
+func (i *itemRepository) ListOrphanImages(ctx context.Context, before time.Time) ([]Image, error) {
+	defer i.metrics.observeQuery("list_orphan_images", time.Now())
+
+	rows, err := i.db.QueryContext(ctx, `
+		SELECT id, name, hash, size, content_type, ref_count, created_at, unreferenced_at
+		FROM images
+		WHERE ref_count = 0 AND unreferenced_at < ?
+		ORDER BY unreferenced_at, id
+	`, before.UTC().Format(time.DateTime))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orphan images: %w", err)
+	}
+	defer rows.Close()
+
+	var images []Image
+	for rows.Next() {
+		var img Image
+		err := rows.Scan(&img.ID, &img.Name, &img.Hash, &img.Size, &img.ContentType, &img.RefCount, &img.CreatedAt, &img.UnreferencedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		images = append(images, img)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+	return images, nil
+}
+
+// DeleteOrphanImage deletes the record of an image no item has used since before,
+// and calls deleteFiles to delete its files before the deletion is committed.
+// It reports false if the image is used again, was saved again, or does not exist,
+// in which case its files are kept.
+// The transaction holds the write lock while the files are deleted, so SaveImage of the same image
+// waits until they are gone and then stores them again. If deleteFiles fails, the record is kept.
+func (i *itemRepository) DeleteOrphanImage(ctx context.Context, name string, before time.Time, deleteFiles func(context.Context) error) (bool, error) {
+	defer i.metrics.observeQuery("delete_orphan_image", time.Now())
+
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM images WHERE name = ? AND ref_count = 0 AND unreferenced_at < ?",
+		name, before.UTC().Format(time.DateTime),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete image %s: %w", name, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if n == 0 {
+		return false, nil
+	}
+	if err := deleteFiles(ctx); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// userRepository is an implementation of UserRepository
+type userRepository struct {
+	// db is a database connection
+	db *sql.DB
+	// metrics records the duration of each operation
+	metrics *Metrics
+}
+
+// NewUserRepository connects db and creates a new userRepository.
+// The schema must have been migrated, e.g. by NewItemRepository. metrics may be nil.
+func NewUserRepository(dbPath string, metrics *Metrics) (UserRepository, error) {
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the database: %w", err)
+	}
+	return &userRepository{db: db, metrics: metrics}, nil
+}
+
+// Close closes the database connection.
+func (u *userRepository) Close() error {
+	return u.db.Close()
+}
+
+// userColumns are the columns scanned into a User.
+const userColumns = "u.id, u.name, u.password_hash, u.created_at"
+
+// CreateUser creates a user. It returns errUserExists if the name is taken, ignoring case.
+func (u *userRepository) CreateUser(ctx context.Context, name, passwordHash string) (*User, error) {
+	defer u.metrics.observeQuery("create_user", time.Now())
+
+	var user User
+	err := u.db.QueryRowContext(ctx, `
+		INSERT INTO users (name, password_hash) VALUES (?, ?)
+		ON CONFLICT (name) DO NOTHING
+		RETURNING id, name, password_hash, created_at
+	`, name, passwordHash,
+	).Scan(&user.ID, &user.Name, &user.PasswordHash, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserExists
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return &user, nil
+}
+
+// GetUserByName returns the user with the given name, ignoring case.
+// It returns errUserNotFound if there is no such user.
+func (u *userRepository) GetUserByName(ctx context.Context, name string) (*User, error) {
+	defer u.metrics.observeQuery("get_user_by_name", time.Now())
+
+	var user User
+	err := u.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users u WHERE u.name = ?", name,
+	).Scan(&user.ID, &user.Name, &user.PasswordHash, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+// GetUserByID returns the user with the given id.
+// It returns errUserNotFound if there is no such user.
+func (u *userRepository) GetUserByID(ctx context.Context, id int) (*User, error) {
+	defer u.metrics.observeQuery("get_user_by_id", time.Now())
+
+	var user User
+	err := u.db.QueryRowContext(ctx,
+		"SELECT "+userColumns+" FROM users u WHERE u.id = ?", id,
+	).Scan(&user.ID, &user.Name, &user.PasswordHash, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user %d: %w", id, err)
+	}
+	return &user, nil
+}
+
+// CreateSession creates a session of the user that expires at expiresAt.
+// Sessions that have already expired are deleted at the same time.
+func (u *userRepository) CreateSession(ctx context.Context, tokenHash string, userID int, expiresAt time.Time) error {
+	defer u.metrics.observeQuery("create_session", time.Now())
+
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM sessions WHERE expires_at < CURRENT_TIMESTAMP")
+	if err != nil {
+		return fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO sessions (token_hash, user_id, expires_at) VALUES (?, ?, ?)",
+		tokenHash, userID, expiresAt.UTC().Format(time.DateTime),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetSessionUser returns the user of the session that has not expired at now.
+// It returns errSessionNotFound if there is no such session.
+func (u *userRepository) GetSessionUser(ctx context.Context, tokenHash string, now time.Time) (*User, error) {
+	defer u.metrics.observeQuery("get_session_user", time.Now())
+
+	var user User
+	err := u.db.QueryRowContext(ctx, `
+		SELECT `+userColumns+`
+		FROM sessions s
+		JOIN users u ON s.user_id = u.id
+		WHERE s.token_hash = ? AND s.expires_at > ?
+	`, tokenHash, now.UTC().Format(time.DateTime)).Scan(&user.ID, &user.Name, &user.PasswordHash, &user.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &user, nil
+}
+
+// DeleteSession deletes the session. Deleting a session that does not exist is not an error.
+func (u *userRepository) DeleteSession(ctx context.Context, tokenHash string) error {
+	defer u.metrics.observeQuery("delete_session", time.Now())
+
+	_, err := u.db.ExecContext(ctx, "DELETE FROM sessions WHERE token_hash = ?", tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
 	return nil
 }
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// maxCategoryLabels is the number of categories that get a label value of their own in
+// mercari_items_created_total. Users create categories, so items in categories beyond it are
+// counted under otherCategory to keep the number of series bounded.
+const maxCategoryLabels = 100
+
+// otherCategory is the category label value of items in categories beyond maxCategoryLabels.
+const otherCategory = "(other)"
+
+// Metrics holds the Prometheus metrics exposed on /metrics.
+// A nil *Metrics records nothing, so handlers and repositories created without metrics still work.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests    *prometheus.CounterVec
+	httpDuration    *prometheus.HistogramVec
+	itemsCreated    *prometheus.CounterVec
+	imageBytes      prometheus.Counter
+	imageDedupeHits prometheus.Counter
+	imagesDeleted   prometheus.Counter
+	dbQueryDuration *prometheus.HistogramVec
+
+	mu         sync.Mutex
+	categories map[string]bool // categories labelled in itemsCreated
+}
+
+// NewMetrics creates the metrics and registers them, together with the Go runtime and process metrics,
+// in a registry of their own.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mercari_http_requests_total",
+			Help: "Number of HTTP requests by route pattern and status code.",
+		}, []string{"route", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mercari_http_request_duration_seconds",
+			Help:    "Latency of HTTP requests by route pattern and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "status"}),
+		itemsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "mercari_items_created_total",
+			Help: "Number of items created by category. Categories beyond the first 100 seen are counted as (other).",
+		}, []string{"category"}),
+		imageBytes: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mercari_image_stored_bytes_total",
+			Help: "Bytes of images written to the image store. Deduplicated uploads are not counted.",
+		}),
+		imageDedupeHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mercari_image_dedupe_hits_total",
+			Help: "Number of uploaded images that were already stored.",
+		}),
+		imagesDeleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "mercari_image_gc_deleted_total",
+			Help: "Number of images deleted by the garbage collector because no item used them.",
+		}),
+		dbQueryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "mercari_db_query_duration_seconds",
+			Help:    "Duration of database operations by repository method.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation"}),
+		categories: make(map[string]bool),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpDuration,
+		m.itemsCreated,
+		m.imageBytes,
+		m.imageDedupeHits,
+		m.imagesDeleted,
+		m.dbQueryDuration,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// observeRequest records a served HTTP request.
+// route is the pattern of the mux that handled the request, or empty if none matched.
+func (m *Metrics) observeRequest(route string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	if route == "" {
+		route = "unmatched"
+	}
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, code).Inc()
+	m.httpDuration.WithLabelValues(route, code).Observe(d.Seconds())
+}
+
+// itemCreated records a created item in the category with the given stored name.
+func (m *Metrics) itemCreated(category string) {
+	if m == nil {
+		return
+	}
+	m.itemsCreated.WithLabelValues(m.categoryLabel(category)).Inc()
+}
+
+// categoryLabel returns the label value of the category, which is otherCategory once
+// maxCategoryLabels other categories have been labelled.
+func (m *Metrics) categoryLabel(category string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.categories[category] {
+		if len(m.categories) >= maxCategoryLabels {
+			return otherCategory
+		}
+		m.categories[category] = true
+	}
+	return category
+}
+
+// imageStored records the size of a newly stored image.
+func (m *Metrics) imageStored(size int64) {
+	if m == nil {
+		return
+	}
+	m.imageBytes.Add(float64(size))
+}
+
+// imageDeduplicated records an upload of an image that was already stored.
+func (m *Metrics) imageDeduplicated() {
+	if m == nil {
+		return
+	}
+	m.imageDedupeHits.Inc()
+}
+
+// imagesCollected records images deleted by the garbage collector.
+func (m *Metrics) imagesCollected(n int) {
+	if m == nil {
+		return
+	}
+	m.imagesDeleted.Add(float64(n))
+}
+
+// observeQuery records the duration of a database operation started at start.
+// It is meant to be deferred at the beginning of a repository method.
+func (m *Metrics) observeQuery(operation string, start time.Time) {
+	if m == nil {
+		return
+	}
+	m.dbQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
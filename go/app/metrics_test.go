@@ -0,0 +1,122 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetrics()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "0" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	})
+	mux.Handle("GET /metrics", m.Handler())
+	h := metricsMiddleware(mux, m)
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/0", "/unknown"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	cases := map[string]struct {
+		route  string
+		status string
+		want   float64
+	}{
+		"requests to the same pattern share a label": {route: "GET /items/{id}", status: "200", want: 2},
+		"status code is a label":                     {route: "GET /items/{id}", status: "404", want: 1},
+		"requests matching no route":                 {route: "unmatched", status: "404", want: 1},
+	}
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got := testutil.ToFloat64(m.httpRequests.WithLabelValues(tt.route, tt.status))
+			if got != tt.want {
+				t.Errorf("expected %v requests, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// the metrics are exposed in the Prometheus text format
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest("GET", "/metrics", nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected status code %d, got %d", http.StatusOK, res.Code)
+	}
+	for _, want := range []string{
+		`mercari_http_requests_total{route="GET /items/{id}",status="200"} 2`,
+		`mercari_http_request_duration_seconds_count{route="GET /items/{id}",status="200"} 2`,
+	} {
+		if !strings.Contains(res.Body.String(), want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+}
+
+func TestStoreImageMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetrics()
+	h := &Handlers{imgStore: NewMemoryImageStore(), metrics: m}
+
+	first := newTestImage(t, "image/png")
+	second := newTestImage(t, "image/gif")
+	// the second upload of the same image is deduplicated
+	for _, img := range [][]byte{first, second, first} {
+		storeTestImage(t, h, img)
+	}
+
+	if got := testutil.ToFloat64(m.imageBytes); got != float64(len(first)+len(second)) {
+		t.Errorf("unexpected stored bytes: %v", got)
+	}
+	if got := testutil.ToFloat64(m.imageDedupeHits); got != 1 {
+		t.Errorf("expected 1 dedupe hit, got %v", got)
+	}
+}
+
+func TestItemCreatedMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetrics()
+	m.itemCreated("fashion")
+	for n := range maxCategoryLabels - 1 {
+		m.itemCreated(fmt.Sprintf("category%d", n))
+	}
+	// a category already labelled keeps its label, and new ones are counted as other
+	m.itemCreated("fashion")
+	m.itemCreated("toys")
+	m.itemCreated("books")
+
+	if got := testutil.CollectAndCount(m.itemsCreated); got != maxCategoryLabels+1 {
+		t.Errorf("expected %d series, got %d", maxCategoryLabels+1, got)
+	}
+	if got := testutil.ToFloat64(m.itemsCreated.WithLabelValues("fashion")); got != 2 {
+		t.Errorf("expected 2 items in fashion, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.itemsCreated.WithLabelValues(otherCategory)); got != 2 {
+		t.Errorf("expected 2 items in %s, got %v", otherCategory, got)
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	t.Parallel()
+
+	// handlers and repositories created without metrics must not panic
+	var m *Metrics
+	m.observeRequest("GET /", http.StatusOK, 0)
+	m.itemCreated("fashion")
+	m.imageStored(1)
+	m.imageDeduplicated()
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAccessLogMiddleware(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		requestID string
+		// propagated reports whether the request ID sent by the client is reused
+		propagated bool
+	}{
+		"ok: propagate the client request ID": {requestID: "abc-123", propagated: true},
+		"ok: generate a request ID":           {requestID: ""},
+		"ok: replace an ID with spaces":       {requestID: "abc 123"},
+		"ok: replace a too long ID":           {requestID: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var ctxID string
+			h := accessLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ctxID = requestIDFromContext(r.Context())
+				w.WriteHeader(http.StatusCreated)
+			}))
+
+			req := httptest.NewRequest("POST", "/items", nil)
+			if tt.requestID != "" {
+				req.Header.Set(requestIDHeader, tt.requestID)
+			}
+			res := httptest.NewRecorder()
+			h.ServeHTTP(res, req)
+
+			got := res.Header().Get(requestIDHeader)
+			if got == "" {
+				t.Fatal("expected a request ID in the response header")
+			}
+			if got != ctxID {
+				t.Errorf("expected the request ID %q in the context, got %q", got, ctxID)
+			}
+			if tt.propagated != (got == tt.requestID) {
+				t.Errorf("unexpected request ID %q for the client request ID %q", got, tt.requestID)
+			}
+			if res.Code != http.StatusCreated {
+				t.Errorf("expected status code %d, got %d", http.StatusCreated, res.Code)
+			}
+		})
+	}
+}
+
+func TestResponseRecorder(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBytes  int64
+	}{
+		"ok: implicit 200": {
+			handler:    func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("hello")) },
+			wantStatus: http.StatusOK,
+			wantBytes:  5,
+		},
+		"ok: no body": {
+			handler:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) },
+			wantStatus: http.StatusNoContent,
+		},
+		"ok: error": {
+			handler:    func(w http.ResponseWriter, r *http.Request) { http.Error(w, "oops", http.StatusBadRequest) },
+			wantStatus: http.StatusBadRequest,
+			wantBytes:  5,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			rec := &responseRecorder{ResponseWriter: httptest.NewRecorder()}
+			tt.handler(rec, httptest.NewRequest("GET", "/", nil))
+			if rec.statusCode() != tt.wantStatus {
+				t.Errorf("expected status code %d, got %d", tt.wantStatus, rec.statusCode())
+			}
+			if rec.bytes != tt.wantBytes {
+				t.Errorf("expected %d bytes, got %d", tt.wantBytes, rec.bytes)
+			}
+		})
+	}
+}
+
+func TestRequestIDHandler(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := slog.New(requestIDHandler{slog.NewJSONHandler(&buf, nil)}).With("component", "test")
+
+	ctx := context.WithValue(context.Background(), requestIDKey{}, "abc-123")
+	logger.InfoContext(ctx, "with request")
+	logger.Info("without request")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d", len(lines))
+	}
+	for i, want := range []string{"abc-123", ""} {
+		var got map[string]any
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatalf("failed to decode log line: %v", err)
+		}
+		id, _ := got["request_id"].(string)
+		if id != want {
+			t.Errorf("line %d: expected request_id %q, got %q", i, want, id)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"mercari-build-training/db/migrations"
+)
+
+// Migration is a numbered schema change.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// MigrationStatus is a migration and when it was applied.
+type MigrationStatus struct {
+	Migration
+	// AppliedAt is nil if the migration is pending.
+	AppliedAt *time.Time
+}
+
+// migrationFileName matches migration file names such as 0001_create_items.sql.
+var migrationFileName = regexp.MustCompile(`^(\d+)_(\w+)\.sql$`)
+
+// loadMigrations reads the migrations in fsys ordered by version.
+func loadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var ms []Migration
+	seen := map[int]string{}
+	for _, e := range entries {
+		m := migrationFileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version: %s", e.Name())
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("duplicated migration version %d: %s and %s", version, other, e.Name())
+		}
+		seen[version] = e.Name()
+
+		b, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", e.Name(), err)
+		}
+		ms = append(ms, Migration{Version: version, Name: m[2], SQL: string(b)})
+	}
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms, nil
+}
+
+// ensureMigrationsTable creates the table recording applied migrations.
+func ensureMigrationsTable(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// GetMigrationStatus returns every embedded migration and when it was applied.
+func GetMigrationStatus(db *sql.DB) ([]MigrationStatus, error) {
+	ms, err := loadMigrations(migrations.FS)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureMigrationsTable(db); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		applied[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate rows: %w", err)
+	}
+
+	statuses := make([]MigrationStatus, 0, len(ms))
+	for _, m := range ms {
+		st := MigrationStatus{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			st.AppliedAt = &at
+		}
+		statuses = append(statuses, st)
+	}
+	return statuses, nil
+}
+
+// Migrate applies every pending migration in version order and returns the applied ones.
+// Each migration runs in its own transaction together with its schema_migrations record.
+func Migrate(db *sql.DB) ([]Migration, error) {
+	statuses, err := GetMigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	for _, st := range statuses {
+		if st.AppliedAt != nil {
+			continue
+		}
+		if err := applyMigration(db, st.Migration); err != nil {
+			return applied, err
+		}
+		slog.Info("applied migration", "version", st.Version, "name", st.Name)
+		applied = append(applied, st.Migration)
+	}
+	return applied, nil
+}
+
+// applyMigration runs a migration and records it in a single transaction.
+func applyMigration(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return fmt.Errorf("failed to apply migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	_, err = tx.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+	return nil
+}
+
+// RunMigrate runs the migrate subcommand against the database at dbPath and returns the exit code.
+//
+//	migrate status  shows every migration and whether it is applied
+//	migrate up      applies pending migrations and shows the result
+func RunMigrate(dbPath string, args []string) int {
+	cmd := "status"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	if len(args) > 1 || (cmd != "status" && cmd != "up") {
+		fmt.Fprintln(os.Stderr, "usage: migrate [status|up]")
+		return 2
+	}
+
+	db, err := sql.Open("sqlite3", sqliteDSN(dbPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to connect to the database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if cmd == "up" {
+		applied, err := Migrate(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("applied %d migration(s)\n", len(applied))
+	}
+
+	statuses, err := GetMigrationStatus(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	printMigrationStatus(os.Stdout, statuses)
+	return 0
+}
+
+// printMigrationStatus writes a table of migrations and when they were applied.
+func printMigrationStatus(w io.Writer, statuses []MigrationStatus) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED AT")
+	for _, st := range statuses {
+		appliedAt := "pending"
+		if st.AppliedAt != nil {
+			appliedAt = st.AppliedAt.Format(time.DateTime)
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", st.Version, st.Name, appliedAt)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		files fstest.MapFS
+		want  []Migration
+		err   bool
+	}{
+		"ok: ordered by version": {
+			files: fstest.MapFS{
+				"0002_add_column.sql":   {Data: []byte("ALTER TABLE t ADD COLUMN c;")},
+				"0001_create_table.sql": {Data: []byte("CREATE TABLE t (id);")},
+				"items_fts.sql":         {Data: []byte("-- not a migration")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "create_table", SQL: "CREATE TABLE t (id);"},
+				{Version: 2, Name: "add_column", SQL: "ALTER TABLE t ADD COLUMN c;"},
+			},
+		},
+		"ng: duplicated version": {
+			files: fstest.MapFS{
+				"0001_create_table.sql": {Data: []byte("CREATE TABLE t (id);")},
+				"01_create_other.sql":   {Data: []byte("CREATE TABLE u (id);")},
+			},
+			err: true,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := loadMigrations(tt.files)
+			if err != nil {
+				if !tt.err {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if tt.err {
+				t.Fatalf("expected an error")
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected migrations (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMigrateE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	f, err := os.CreateTemp(".", "*.sqlite3")
+	if err != nil {
+		t.Fatalf("failed to create database file: %v", err)
+	}
+	f.Close()
+	t.Cleanup(func() { os.Remove(f.Name()) })
+
+	db, err := sql.Open("sqlite3", f.Name())
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// a database created by the schema before migrations were introduced
+	_, err = db.Exec(`
+		CREATE TABLE categories (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT UNIQUE NOT NULL);
+		CREATE TABLE items (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			category_id INTEGER NOT NULL,
+			image_name TEXT NOT NULL,
+			FOREIGN KEY (category_id) REFERENCES categories(id)
+		);
+		INSERT INTO categories (name) VALUES ('fashion');
+		INSERT INTO items (name, category_id, image_name) VALUES ('jacket', 1, 'default.jpg');
+	`)
+	if err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+
+	applied, err := Migrate(db)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if len(applied) == 0 {
+		t.Fatalf("expected migrations to be applied")
+	}
+
+	// applying again is a no-op
+	applied, err = Migrate(db)
+	if err != nil {
+		t.Fatalf("failed to migrate again: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected no migrations to be applied, got %d", len(applied))
+	}
+
+	statuses, err := GetMigrationStatus(db)
+	if err != nil {
+		t.Fatalf("failed to get migration status: %v", err)
+	}
+	for _, st := range statuses {
+		if st.AppliedAt == nil {
+			t.Errorf("expected migration %04d_%s to be applied", st.Version, st.Name)
+		}
+	}
+
+	// existing items survive and gain the new columns
+	item, err := (&itemRepository{db: db}).GetByID(t.Context(), 1)
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if item.Name != "jacket" || item.Category != "fashion" || item.CreatedAt.IsZero() {
+		t.Errorf("unexpected item: %+v", item)
+	}
+}
@@ -11,7 +11,10 @@ package app
 
 import (
 	context "context"
+	sql "database/sql"
+	io "io"
 	reflect "reflect"
+	time "time"
 
 	gomock "go.uber.org/mock/gomock"
 )
@@ -40,19 +43,107 @@ func (m *MockItemRepository) EXPECT() *MockItemRepositoryMockRecorder {
 	return m.recorder
 }
 
-// GetItems mocks base method.
-func (m *MockItemRepository) GetItems() ([]Item, error) {
+// Close mocks base method.
+func (m *MockItemRepository) Close() error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetItems")
-	ret0, _ := ret[0].([]Item)
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockItemRepositoryMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockItemRepository)(nil).Close))
+}
+
+// CreateCategory mocks base method.
+func (m *MockItemRepository) CreateCategory(ctx context.Context, name string) (*Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCategory", ctx, name)
+	ret0, _ := ret[0].(*Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCategory indicates an expected call of CreateCategory.
+func (mr *MockItemRepositoryMockRecorder) CreateCategory(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCategory", reflect.TypeOf((*MockItemRepository)(nil).CreateCategory), ctx, name)
+}
+
+// Delete mocks base method.
+func (m *MockItemRepository) Delete(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockItemRepositoryMockRecorder) Delete(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockItemRepository)(nil).Delete), ctx, id)
+}
+
+// DeleteOrphanImage mocks base method.
+func (m *MockItemRepository) DeleteOrphanImage(ctx context.Context, name string, before time.Time, deleteFiles func(context.Context) error) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOrphanImage", ctx, name, before, deleteFiles)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteOrphanImage indicates an expected call of DeleteOrphanImage.
+func (mr *MockItemRepositoryMockRecorder) DeleteOrphanImage(ctx, name, before, deleteFiles any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOrphanImage", reflect.TypeOf((*MockItemRepository)(nil).DeleteOrphanImage), ctx, name, before, deleteFiles)
+}
+
+// GetByID mocks base method.
+func (m *MockItemRepository) GetByID(ctx context.Context, id int) (*Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByID", ctx, id)
+	ret0, _ := ret[0].(*Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByID indicates an expected call of GetByID.
+func (mr *MockItemRepositoryMockRecorder) GetByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByID", reflect.TypeOf((*MockItemRepository)(nil).GetByID), ctx, id)
+}
+
+// GetCategoryByName mocks base method.
+func (m *MockItemRepository) GetCategoryByName(ctx context.Context, name string) (*Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCategoryByName", ctx, name)
+	ret0, _ := ret[0].(*Category)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetItems indicates an expected call of GetItems.
-func (mr *MockItemRepositoryMockRecorder) GetItems() *gomock.Call {
+// GetCategoryByName indicates an expected call of GetCategoryByName.
+func (mr *MockItemRepositoryMockRecorder) GetCategoryByName(ctx, name any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetItems", reflect.TypeOf((*MockItemRepository)(nil).GetItems))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCategoryByName", reflect.TypeOf((*MockItemRepository)(nil).GetCategoryByName), ctx, name)
+}
+
+// GetSellerID mocks base method.
+func (m *MockItemRepository) GetSellerID(ctx context.Context, id int) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSellerID", ctx, id)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSellerID indicates an expected call of GetSellerID.
+func (mr *MockItemRepositoryMockRecorder) GetSellerID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSellerID", reflect.TypeOf((*MockItemRepository)(nil).GetSellerID), ctx, id)
 }
 
 // Insert mocks base method.
@@ -68,3 +159,477 @@ func (mr *MockItemRepositoryMockRecorder) Insert(ctx, item any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Insert", reflect.TypeOf((*MockItemRepository)(nil).Insert), ctx, item)
 }
+
+// ListCategories mocks base method.
+func (m *MockItemRepository) ListCategories(ctx context.Context) ([]Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCategories", ctx)
+	ret0, _ := ret[0].([]Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCategories indicates an expected call of ListCategories.
+func (mr *MockItemRepositoryMockRecorder) ListCategories(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCategories", reflect.TypeOf((*MockItemRepository)(nil).ListCategories), ctx)
+}
+
+// ListItems mocks base method.
+func (m *MockItemRepository) ListItems(ctx context.Context, q ItemQuery) ([]Item, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListItems", ctx, q)
+	ret0, _ := ret[0].([]Item)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// ListItems indicates an expected call of ListItems.
+func (mr *MockItemRepositoryMockRecorder) ListItems(ctx, q any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListItems", reflect.TypeOf((*MockItemRepository)(nil).ListItems), ctx, q)
+}
+
+// ListOrders mocks base method.
+func (m *MockItemRepository) ListOrders(ctx context.Context, userID int, role OrderRole) ([]Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOrders", ctx, userID, role)
+	ret0, _ := ret[0].([]Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOrders indicates an expected call of ListOrders.
+func (mr *MockItemRepositoryMockRecorder) ListOrders(ctx, userID, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrders", reflect.TypeOf((*MockItemRepository)(nil).ListOrders), ctx, userID, role)
+}
+
+// ListOrphanImages mocks base method.
+func (m *MockItemRepository) ListOrphanImages(ctx context.Context, before time.Time) ([]Image, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOrphanImages", ctx, before)
+	ret0, _ := ret[0].([]Image)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOrphanImages indicates an expected call of ListOrphanImages.
+func (mr *MockItemRepositoryMockRecorder) ListOrphanImages(ctx, before any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOrphanImages", reflect.TypeOf((*MockItemRepository)(nil).ListOrphanImages), ctx, before)
+}
+
+// MergeCategories mocks base method.
+func (m *MockItemRepository) MergeCategories(ctx context.Context, srcID, dstID int) (*Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergeCategories", ctx, srcID, dstID)
+	ret0, _ := ret[0].(*Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MergeCategories indicates an expected call of MergeCategories.
+func (mr *MockItemRepositoryMockRecorder) MergeCategories(ctx, srcID, dstID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergeCategories", reflect.TypeOf((*MockItemRepository)(nil).MergeCategories), ctx, srcID, dstID)
+}
+
+// MigrationStatus mocks base method.
+func (m *MockItemRepository) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MigrationStatus", ctx)
+	ret0, _ := ret[0].([]MigrationStatus)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MigrationStatus indicates an expected call of MigrationStatus.
+func (mr *MockItemRepositoryMockRecorder) MigrationStatus(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrationStatus", reflect.TypeOf((*MockItemRepository)(nil).MigrationStatus), ctx)
+}
+
+// Ping mocks base method.
+func (m *MockItemRepository) Ping(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Ping", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Ping indicates an expected call of Ping.
+func (mr *MockItemRepositoryMockRecorder) Ping(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ping", reflect.TypeOf((*MockItemRepository)(nil).Ping), ctx)
+}
+
+// Purchase mocks base method.
+func (m *MockItemRepository) Purchase(ctx context.Context, itemID, buyerID int) (*Order, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Purchase", ctx, itemID, buyerID)
+	ret0, _ := ret[0].(*Order)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Purchase indicates an expected call of Purchase.
+func (mr *MockItemRepositoryMockRecorder) Purchase(ctx, itemID, buyerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Purchase", reflect.TypeOf((*MockItemRepository)(nil).Purchase), ctx, itemID, buyerID)
+}
+
+// RenameCategory mocks base method.
+func (m *MockItemRepository) RenameCategory(ctx context.Context, id int, name string) (*Category, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenameCategory", ctx, id, name)
+	ret0, _ := ret[0].(*Category)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenameCategory indicates an expected call of RenameCategory.
+func (mr *MockItemRepositoryMockRecorder) RenameCategory(ctx, id, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenameCategory", reflect.TypeOf((*MockItemRepository)(nil).RenameCategory), ctx, id, name)
+}
+
+// Restore mocks base method.
+func (m *MockItemRepository) Restore(ctx context.Context, id int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockItemRepositoryMockRecorder) Restore(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockItemRepository)(nil).Restore), ctx, id)
+}
+
+// SaveImage mocks base method.
+func (m *MockItemRepository) SaveImage(ctx context.Context, img *Image) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveImage", ctx, img)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveImage indicates an expected call of SaveImage.
+func (mr *MockItemRepositoryMockRecorder) SaveImage(ctx, img any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveImage", reflect.TypeOf((*MockItemRepository)(nil).SaveImage), ctx, img)
+}
+
+// Search mocks base method.
+func (m *MockItemRepository) Search(ctx context.Context, query string) ([]Item, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Search", ctx, query)
+	ret0, _ := ret[0].([]Item)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Search indicates an expected call of Search.
+func (mr *MockItemRepositoryMockRecorder) Search(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Search", reflect.TypeOf((*MockItemRepository)(nil).Search), ctx, query)
+}
+
+// Update mocks base method.
+func (m *MockItemRepository) Update(ctx context.Context, item *Item) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, item)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockItemRepositoryMockRecorder) Update(ctx, item any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockItemRepository)(nil).Update), ctx, item)
+}
+
+// UpdateStatus mocks base method.
+func (m *MockItemRepository) UpdateStatus(ctx context.Context, id int, from, to ItemStatus) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateStatus", ctx, id, from, to)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateStatus indicates an expected call of UpdateStatus.
+func (mr *MockItemRepositoryMockRecorder) UpdateStatus(ctx, id, from, to any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStatus", reflect.TypeOf((*MockItemRepository)(nil).UpdateStatus), ctx, id, from, to)
+}
+
+// MockUserRepository is a mock of UserRepository interface.
+type MockUserRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserRepositoryMockRecorder
+	isgomock struct{}
+}
+
+// MockUserRepositoryMockRecorder is the mock recorder for MockUserRepository.
+type MockUserRepositoryMockRecorder struct {
+	mock *MockUserRepository
+}
+
+// NewMockUserRepository creates a new mock instance.
+func NewMockUserRepository(ctrl *gomock.Controller) *MockUserRepository {
+	mock := &MockUserRepository{ctrl: ctrl}
+	mock.recorder = &MockUserRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Close mocks base method.
+func (m *MockUserRepository) Close() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Close")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Close indicates an expected call of Close.
+func (mr *MockUserRepositoryMockRecorder) Close() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Close", reflect.TypeOf((*MockUserRepository)(nil).Close))
+}
+
+// CreateSession mocks base method.
+func (m *MockUserRepository) CreateSession(ctx context.Context, tokenHash string, userID int, expiresAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, tokenHash, userID, expiresAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockUserRepositoryMockRecorder) CreateSession(ctx, tokenHash, userID, expiresAt any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockUserRepository)(nil).CreateSession), ctx, tokenHash, userID, expiresAt)
+}
+
+// CreateUser mocks base method.
+func (m *MockUserRepository) CreateUser(ctx context.Context, name, passwordHash string) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, name, passwordHash)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockUserRepositoryMockRecorder) CreateUser(ctx, name, passwordHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepository)(nil).CreateUser), ctx, name, passwordHash)
+}
+
+// DeleteSession mocks base method.
+func (m *MockUserRepository) DeleteSession(ctx context.Context, tokenHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSession", ctx, tokenHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteSession indicates an expected call of DeleteSession.
+func (mr *MockUserRepositoryMockRecorder) DeleteSession(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSession", reflect.TypeOf((*MockUserRepository)(nil).DeleteSession), ctx, tokenHash)
+}
+
+// GetSessionUser mocks base method.
+func (m *MockUserRepository) GetSessionUser(ctx context.Context, tokenHash string, now time.Time) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSessionUser", ctx, tokenHash, now)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSessionUser indicates an expected call of GetSessionUser.
+func (mr *MockUserRepositoryMockRecorder) GetSessionUser(ctx, tokenHash, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionUser", reflect.TypeOf((*MockUserRepository)(nil).GetSessionUser), ctx, tokenHash, now)
+}
+
+// GetUserByID mocks base method.
+func (m *MockUserRepository) GetUserByID(ctx context.Context, id int) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByID", ctx, id)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByID indicates an expected call of GetUserByID.
+func (mr *MockUserRepositoryMockRecorder) GetUserByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByID", reflect.TypeOf((*MockUserRepository)(nil).GetUserByID), ctx, id)
+}
+
+// GetUserByName mocks base method.
+func (m *MockUserRepository) GetUserByName(ctx context.Context, name string) (*User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByName", ctx, name)
+	ret0, _ := ret[0].(*User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByName indicates an expected call of GetUserByName.
+func (mr *MockUserRepositoryMockRecorder) GetUserByName(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByName", reflect.TypeOf((*MockUserRepository)(nil).GetUserByName), ctx, name)
+}
+
+// MockImageStore is a mock of ImageStore interface.
+type MockImageStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockImageStoreMockRecorder
+	isgomock struct{}
+}
+
+// MockImageStoreMockRecorder is the mock recorder for MockImageStore.
+type MockImageStoreMockRecorder struct {
+	mock *MockImageStore
+}
+
+// NewMockImageStore creates a new mock instance.
+func NewMockImageStore(ctrl *gomock.Controller) *MockImageStore {
+	mock := &MockImageStore{ctrl: ctrl}
+	mock.recorder = &MockImageStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockImageStore) EXPECT() *MockImageStoreMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockImageStore) Delete(ctx context.Context, name string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, name)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockImageStoreMockRecorder) Delete(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockImageStore)(nil).Delete), ctx, name)
+}
+
+// Exists mocks base method.
+func (m *MockImageStore) Exists(ctx context.Context, name string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Exists", ctx, name)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Exists indicates an expected call of Exists.
+func (mr *MockImageStoreMockRecorder) Exists(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Exists", reflect.TypeOf((*MockImageStore)(nil).Exists), ctx, name)
+}
+
+// Get mocks base method.
+func (m *MockImageStore) Get(ctx context.Context, name string) (io.ReadCloser, *ImageInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, name)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(*ImageInfo)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockImageStoreMockRecorder) Get(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockImageStore)(nil).Get), ctx, name)
+}
+
+// Put mocks base method.
+func (m *MockImageStore) Put(ctx context.Context, name string, r io.Reader, size int64, contentType string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", ctx, name, r, size, contentType)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockImageStoreMockRecorder) Put(ctx, name, r, size, contentType any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockImageStore)(nil).Put), ctx, name, r, size, contentType)
+}
+
+// Stat mocks base method.
+func (m *MockImageStore) Stat(ctx context.Context, name string) (*ImageInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stat", ctx, name)
+	ret0, _ := ret[0].(*ImageInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Stat indicates an expected call of Stat.
+func (mr *MockImageStoreMockRecorder) Stat(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stat", reflect.TypeOf((*MockImageStore)(nil).Stat), ctx, name)
+}
+
+// Mockqueryer is a mock of queryer interface.
+type Mockqueryer struct {
+	ctrl     *gomock.Controller
+	recorder *MockqueryerMockRecorder
+	isgomock struct{}
+}
+
+// MockqueryerMockRecorder is the mock recorder for Mockqueryer.
+type MockqueryerMockRecorder struct {
+	mock *Mockqueryer
+}
+
+// NewMockqueryer creates a new mock instance.
+func NewMockqueryer(ctrl *gomock.Controller) *Mockqueryer {
+	mock := &Mockqueryer{ctrl: ctrl}
+	mock.recorder = &MockqueryerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockqueryer) EXPECT() *MockqueryerMockRecorder {
+	return m.recorder
+}
+
+// QueryRowContext mocks base method.
+func (m *Mockqueryer) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx, query}
+	for _, a := range args {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "QueryRowContext", varargs...)
+	ret0, _ := ret[0].(*sql.Row)
+	return ret0
+}
+
+// QueryRowContext indicates an expected call of QueryRowContext.
+func (mr *MockqueryerMockRecorder) QueryRowContext(ctx, query any, args ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx, query}, args...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "QueryRowContext", reflect.TypeOf((*Mockqueryer)(nil).QueryRowContext), varargs...)
+}
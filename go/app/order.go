@@ -0,0 +1,86 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// parseOrderRole parses the side of the orders to list.
+// An empty role is the buyer.
+func parseOrderRole(s string) (OrderRole, error) {
+	switch role := OrderRole(s); role {
+	case "":
+		return RoleBuyer, nil
+	case RoleBuyer, RoleSeller:
+		return role, nil
+	default:
+		return "", fmt.Errorf("unknown role %q: must be %s or %s", s, RoleBuyer, RoleSeller)
+	}
+}
+
+// Purchase is a handler to buy an item for POST /items/{id}/purchase .
+// The item must be on sale and sold by another user. It is marked sold and an order is created
+// in one transaction, so only one of concurrent buyers succeeds and the others get 409 Conflict.
+func (s *Handlers) Purchase(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := currentUser(w, r)
+	if user == nil {
+		return
+	}
+
+	id, err := parsePathID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	order, err := s.itemRepo.Purchase(ctx, id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, errItemNotFound):
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("item %d not found", id))
+		case errors.Is(err, errOwnItem):
+			writeJSONError(w, http.StatusForbidden, fmt.Sprintf("item %d is your own", id))
+		case errors.Is(err, errItemNotOnSale):
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("item %d is not on sale", id))
+		default:
+			slog.ErrorContext(ctx, "failed to purchase item: ", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	slog.InfoContext(ctx, "item purchased", "order", order.ID, "item", order.ItemID, "buyer", order.BuyerID)
+
+	writeJSON(w, r, http.StatusCreated, order)
+}
+
+type GetOrdersResponse struct {
+	Orders []Order `json:"orders"`
+}
+
+// GetOrders is a handler to return the orders of the current user for GET /orders .
+// The role query parameter selects the items the user bought (buyer, the default)
+// or the items the user sold (seller).
+func (s *Handlers) GetOrders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := currentUser(w, r)
+	if user == nil {
+		return
+	}
+
+	role, err := parseOrderRole(r.URL.Query().Get("role"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	orders, err := s.itemRepo.ListOrders(ctx, user.ID, role)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list orders: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, GetOrdersResponse{Orders: orders})
+}
@@ -0,0 +1,335 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestParseOrderRole(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		role    string
+		want    OrderRole
+		wantErr bool
+	}{
+		"ok: buyer by default": {role: "", want: RoleBuyer},
+		"ok: buyer":            {role: "buyer", want: RoleBuyer},
+		"ok: seller":           {role: "seller", want: RoleSeller},
+		"ng: unknown role":     {role: "admin", wantErr: true},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseOrderRole(tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected role %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPurchase(t *testing.T) {
+	t.Parallel()
+
+	order := &Order{ID: 1, ItemID: 1, BuyerID: 1, SellerID: 2, Price: 1500, Currency: "JPY"}
+	cases := map[string]struct {
+		anonymous bool
+		injector  func(m *MockItemRepository)
+		code      int
+	}{
+		"ok: purchased": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Purchase(gomock.Any(), 1, 1).Return(order, nil)
+			},
+			code: http.StatusCreated,
+		},
+		"ng: not on sale": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Purchase(gomock.Any(), 1, 1).Return(nil, errItemNotOnSale)
+			},
+			code: http.StatusConflict,
+		},
+		"ng: own item": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Purchase(gomock.Any(), 1, 1).Return(nil, errOwnItem)
+			},
+			code: http.StatusForbidden,
+		},
+		"ng: item not found": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Purchase(gomock.Any(), 1, 1).Return(nil, errItemNotFound)
+			},
+			code: http.StatusNotFound,
+		},
+		"ng: not logged in": {
+			anonymous: true,
+			injector:  func(m *MockItemRepository) {},
+			code:      http.StatusUnauthorized,
+		},
+		"ng: failed to purchase": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Purchase(gomock.Any(), 1, 1).Return(nil, errors.New("failed to purchase"))
+			},
+			code: http.StatusInternalServerError,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			h := &Handlers{itemRepo: mockIR}
+
+			req := httptest.NewRequest("POST", "/items/1/purchase", nil)
+			req.SetPathValue("id", "1")
+			if !tt.anonymous {
+				req = withUser(req, &User{ID: 1, Name: "taro"})
+			}
+			rr := httptest.NewRecorder()
+			h.Purchase(rr, req)
+
+			if rr.Code != tt.code {
+				t.Errorf("expected status code %d, got %d: %s", tt.code, rr.Code, rr.Body)
+			}
+			if tt.code != http.StatusCreated {
+				return
+			}
+			var got Order
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if got.ID != order.ID || got.ItemID != order.ItemID || got.Price != order.Price {
+				t.Errorf("expected order %+v, got %+v", order, got)
+			}
+		})
+	}
+}
+
+func TestGetOrders(t *testing.T) {
+	t.Parallel()
+
+	orders := []Order{{ID: 1, ItemID: 1, BuyerID: 1, SellerID: 2, Price: 1500, Currency: "JPY"}}
+	cases := map[string]struct {
+		query     string
+		anonymous bool
+		injector  func(m *MockItemRepository)
+		code      int
+	}{
+		"ok: buyer by default": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListOrders(gomock.Any(), 1, RoleBuyer).Return(orders, nil)
+			},
+			code: http.StatusOK,
+		},
+		"ok: seller": {
+			query: "?role=seller",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListOrders(gomock.Any(), 1, RoleSeller).Return(orders, nil)
+			},
+			code: http.StatusOK,
+		},
+		"ng: unknown role": {
+			query:    "?role=admin",
+			injector: func(m *MockItemRepository) {},
+			code:     http.StatusBadRequest,
+		},
+		"ng: not logged in": {
+			anonymous: true,
+			injector:  func(m *MockItemRepository) {},
+			code:      http.StatusUnauthorized,
+		},
+		"ng: failed to list": {
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListOrders(gomock.Any(), 1, RoleBuyer).Return(nil, errors.New("failed to list"))
+			},
+			code: http.StatusInternalServerError,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			h := &Handlers{itemRepo: mockIR}
+
+			req := httptest.NewRequest("GET", "/orders"+tt.query, nil)
+			if !tt.anonymous {
+				req = withUser(req, &User{ID: 1, Name: "taro"})
+			}
+			rr := httptest.NewRecorder()
+			h.GetOrders(rr, req)
+
+			if rr.Code != tt.code {
+				t.Errorf("expected status code %d, got %d: %s", tt.code, rr.Code, rr.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var got GetOrdersResponse
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if len(got.Orders) != len(orders) {
+				t.Errorf("expected %d orders, got %d", len(orders), len(got.Orders))
+			}
+		})
+	}
+}
+
+func TestPurchaseE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	ctx := context.Background()
+	userRepo := &userRepository{db: db}
+	seller, err := userRepo.CreateUser(ctx, "seller", "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	const buyers = 10
+	buyerIDs := make([]int, buyers)
+	for n := range buyers {
+		buyer, err := userRepo.CreateUser(ctx, fmt.Sprintf("buyer%d", n), "hash")
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		buyerIDs[n] = buyer.ID
+	}
+
+	repo := &itemRepository{db: db}
+	for _, item := range []*Item{
+		{Name: "jacket", Category: "fashion", ImageName: "default.jpg", SellerID: seller.ID, Price: 1500, Currency: "JPY"},
+		{Name: "jeans", Category: "fashion", ImageName: "default.jpg", SellerID: seller.ID, Price: 2000, Currency: "USD"},
+		{Name: "jersey", Category: "fashion", ImageName: "default.jpg", Price: 500, Currency: "JPY"},
+	} {
+		if err := repo.Insert(ctx, item); err != nil {
+			t.Fatalf("failed to insert item: %v", err)
+		}
+	}
+
+	// all buyers try to buy the jacket at once, and exactly one succeeds
+	var wg sync.WaitGroup
+	orders := make([]*Order, buyers)
+	errs := make([]error, buyers)
+	for n, buyerID := range buyerIDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			orders[n], errs[n] = repo.Purchase(ctx, 1, buyerID)
+		}()
+	}
+	wg.Wait()
+
+	var winner *Order
+	for n, err := range errs {
+		switch {
+		case err == nil:
+			if winner != nil {
+				t.Fatalf("expected only one purchase to succeed, got orders %+v and %+v", winner, orders[n])
+			}
+			winner = orders[n]
+		case !errors.Is(err, errItemNotOnSale):
+			t.Errorf("expected errItemNotOnSale, got %v", err)
+		}
+	}
+	if winner == nil {
+		t.Fatalf("expected one purchase to succeed")
+	}
+	if winner.ItemID != 1 || winner.SellerID != seller.ID || winner.Price != 1500 || winner.Currency != "JPY" {
+		t.Errorf("unexpected order %+v", winner)
+	}
+
+	item, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if item.Status != StatusSold {
+		t.Errorf("expected status %s, got %s", StatusSold, item.Status)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM orders WHERE item_id = 1").Scan(&count); err != nil {
+		t.Fatalf("failed to count orders: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 order, got %d", count)
+	}
+
+	// items that cannot be bought leave no order behind
+	for _, tt := range []struct {
+		itemID, buyerID int
+		want            error
+	}{
+		{2, seller.ID, errOwnItem},
+		{3, buyerIDs[0], errItemNotOnSale},
+		{4, buyerIDs[0], errItemNotFound},
+	} {
+		if _, err := repo.Purchase(ctx, tt.itemID, tt.buyerID); !errors.Is(err, tt.want) {
+			t.Errorf("expected %v buying item %d, got %v", tt.want, tt.itemID, err)
+		}
+	}
+	item, err = repo.GetByID(ctx, 2)
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if item.Status != StatusOnSale {
+		t.Errorf("expected status %s, got %s", StatusOnSale, item.Status)
+	}
+
+	// the jeans are bought by the same buyer, who sees both orders newest first
+	second, err := repo.Purchase(ctx, 2, winner.BuyerID)
+	if err != nil {
+		t.Fatalf("failed to purchase item: %v", err)
+	}
+	for _, tt := range []struct {
+		userID int
+		role   OrderRole
+		want   []int
+	}{
+		{winner.BuyerID, RoleBuyer, []int{second.ID, winner.ID}},
+		{seller.ID, RoleSeller, []int{second.ID, winner.ID}},
+		{seller.ID, RoleBuyer, nil},
+	} {
+		got, err := repo.ListOrders(ctx, tt.userID, tt.role)
+		if err != nil {
+			t.Fatalf("failed to list orders: %v", err)
+		}
+		var ids []int
+		for _, o := range got {
+			ids = append(ids, o.ID)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(tt.want) {
+			t.Errorf("expected %s orders of user %d to be %v, got %v", tt.role, tt.userID, tt.want, ids)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultCurrency is the currency of items listed without one.
+	defaultCurrency = "JPY"
+	// maxPrice is the highest price of an item in minor units.
+	// It is far below 2^53, so prices are exact as JSON numbers in JavaScript.
+	maxPrice int64 = 9_999_999
+)
+
+// supportedCurrencies are the ISO 4217 codes items can be priced in.
+var supportedCurrencies = []string{"EUR", "JPY", "USD"}
+
+// parsePrice parses a price in minor units, e.g. cents for USD and yen for JPY.
+func parsePrice(s string) (int64, error) {
+	price, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errors.New("price must be an integer in minor units")
+	}
+	if err := validatePrice(price); err != nil {
+		return 0, err
+	}
+	return price, nil
+}
+
+// validatePrice validates a price in minor units.
+func validatePrice(price int64) error {
+	if price < 0 {
+		return errors.New("price must not be negative")
+	}
+	if price > maxPrice {
+		return fmt.Errorf("price must be at most %d", maxPrice)
+	}
+	return nil
+}
+
+// parseCurrency parses a currency code, ignoring case.
+// An empty code is the default currency.
+func parseCurrency(s string) (string, error) {
+	if s == "" {
+		return defaultCurrency, nil
+	}
+	currency := strings.ToUpper(s)
+	if !slices.Contains(supportedCurrencies, currency) {
+		return "", fmt.Errorf("unsupported currency %q: must be one of %s", s, strings.Join(supportedCurrencies, ", "))
+	}
+	return currency, nil
+}
+
+// parsePriceParam parses the optional price query parameter name.
+// It returns nil if the parameter is not specified.
+func parsePriceParam(query url.Values, name string) (*int64, error) {
+	s := query.Get(name)
+	if s == "" {
+		return nil, nil
+	}
+	price, err := parsePrice(s)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &price, nil
+}
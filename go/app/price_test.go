@@ -0,0 +1,70 @@
+package app
+
+import (
+	"testing"
+)
+
+func TestParsePrice(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		s    string
+		want int64
+		err  bool
+	}{
+		"ok: zero":            {s: "0", want: 0},
+		"ok: minor units":     {s: "1999", want: 1999},
+		"ok: maximum":         {s: "9999999", want: maxPrice},
+		"ng: negative":        {s: "-1", err: true},
+		"ng: above maximum":   {s: "10000000", err: true},
+		"ng: fraction":        {s: "19.99", err: true},
+		"ng: exponent":        {s: "1e3", err: true},
+		"ng: not a number":    {s: "free", err: true},
+		"ng: empty":           {s: "", err: true},
+		"ng: overflows int64": {s: "99999999999999999999", err: true},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parsePrice(tt.s)
+			if (err != nil) != tt.err {
+				t.Fatalf("parsePrice(%q) error = %v, want error %v", tt.s, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("parsePrice(%q) = %d, want %d", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		s    string
+		want string
+		err  bool
+	}{
+		"ok: default":     {s: "", want: defaultCurrency},
+		"ok: upper case":  {s: "USD", want: "USD"},
+		"ok: lower case":  {s: "eur", want: "EUR"},
+		"ng: unsupported": {s: "BTC", err: true},
+		"ng: not a code":  {s: "yen", err: true},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := parseCurrency(tt.s)
+			if (err != nil) != tt.err {
+				t.Fatalf("parseCurrency(%q) error = %v, want error %v", tt.s, err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("parseCurrency(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
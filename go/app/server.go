@@ -1,75 +1,278 @@
 package app
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
+	"net"
 	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"path"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 )
 
+// Server is configured by LoadServer. See config.go for the configuration sources.
 type Server struct {
 	// Port is the port number to listen on.
 	Port string
-	// ImageDirPath is the path to the directory storing images.
+	// DBPath is the path to the SQLite database file.
+	DBPath string
+	// ImageDirPath is the path to the directory storing images in the local image store.
 	ImageDirPath string
+	// ImageStore is where images are stored: local or s3.
+	ImageStore string
+	// S3 configures the s3 image store.
+	S3 S3Config
+	// AllowedOrigins are the origins and origin patterns allowed to call the API from browsers.
+	AllowedOrigins []string
+	// CORSMaxAge is how long browsers may cache preflight responses.
+	CORSMaxAge time.Duration
+	// CORSAllowCredentials allows cross-origin requests with credentials.
+	CORSAllowCredentials bool
+	// CORSExposedHeaders are the response headers scripts on allowed origins may read.
+	CORSExposedHeaders []string
+	// LogLevel is the minimum level of logs to output.
+	LogLevel slog.Level
+	// LogFormat is the format of logs, json or text.
+	LogFormat string
+	// MaxUploadBytes is the maximum size of a request uploading an image.
+	MaxUploadBytes int64
+	// AdminToken is the token admin requests send. Admin-only features are disabled when it is empty.
+	AdminToken string
+	// StrictCategories rejects items with unknown categories instead of creating them.
+	StrictCategories bool
+	// ImageGCInterval is how often images no item uses are deleted. 0 disables the garbage collector.
+	ImageGCInterval time.Duration
+	// ImageGCGracePeriod is how long an image must be unused before it is deleted.
+	ImageGCGracePeriod time.Duration
 }
 
+// dsn returns the data source name of the SQLite database.
+func (s Server) dsn() string {
+	return sqliteDSN(s.DBPath)
+}
+
+// cors returns the CORS configuration of the API.
+func (s Server) cors() CORSConfig {
+	return CORSConfig{
+		AllowedOrigins:   s.AllowedOrigins,
+		AllowedHeaders:   corsRequestHeaders,
+		ExposedHeaders:   s.CORSExposedHeaders,
+		MaxAge:           s.CORSMaxAge,
+		AllowCredentials: s.CORSAllowCredentials,
+	}
+}
+
+// sqliteDSN returns the data source name of the SQLite database file at path.
+// The file is created if it does not exist.
+// Connections wait up to 5 seconds for a lock held by another connection instead of failing.
+func sqliteDSN(path string) string {
+	return "file:" + path + "?mode=rwc&_busy_timeout=5000"
+}
+
+const (
+	// readHeaderTimeout limits how long a client may take to send request headers.
+	readHeaderTimeout = 10 * time.Second
+	// readTimeout limits how long a client may take to send a whole request, including uploads.
+	readTimeout = 60 * time.Second
+	// writeTimeout limits how long a handler may take to write a response.
+	writeTimeout = 60 * time.Second
+	// idleTimeout limits how long a keep-alive connection stays open between requests.
+	idleTimeout = 120 * time.Second
+	// shutdownTimeout limits how long in-flight requests are drained on shutdown.
+	// It is shorter than the 10 seconds docker stop waits before killing the process.
+	shutdownTimeout = 8 * time.Second
+)
+
 // Run is a method to start the server.
-// This method returns 0 if the server started successfully, and 1 otherwise.
+// The server runs until it receives SIGINT or SIGTERM, and then drains in-flight requests.
+// This method returns 0 if the server stopped gracefully, and 1 otherwise.
 func (s Server) Run() int {
 	// set up logger
-	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	// STEP 4-6: the log level is DEBUG unless configured otherwise
+	logger := s.newLogger()
 	slog.SetDefault(logger)
-	// STEP 4-6: set the log level to DEBUG
-	slog.SetLogLoggerLevel(slog.LevelDebug)
-
-	// set up CORS settings
-	frontURL, found := os.LookupEnv("FRONT_URL")
-	if !found {
-		frontURL = "http://localhost:3000"
-	}
 
 	// STEP 5-1: set up the database connection
 
 	// set up handlers
-	itemRepo, err := NewItemRepository("file:./db/mercari.sqlite3?mode=rwc")
+	metrics := NewMetrics()
+	itemRepo, err := NewItemRepository(s.dsn(), metrics)
 	if err != nil {
 		slog.Error("failed to create item repository: ", "error", err)
 		return 1
 	}
-	h := &Handlers{imgDirPath: s.ImageDirPath, itemRepo: itemRepo}
+	defer func() {
+		if err := itemRepo.Close(); err != nil {
+			slog.Error("failed to close item repository: ", "error", err)
+		}
+	}()
+	userRepo, err := NewUserRepository(s.dsn(), metrics)
+	if err != nil {
+		slog.Error("failed to create user repository: ", "error", err)
+		return 1
+	}
+	defer func() {
+		if err := userRepo.Close(); err != nil {
+			slog.Error("failed to close user repository: ", "error", err)
+		}
+	}()
+	imgStore, err := s.newImageStore(context.Background())
+	if err != nil {
+		slog.Error("failed to create image store: ", "error", err)
+		return 1
+	}
+	h := &Handlers{
+		imgStore:         imgStore,
+		itemRepo:         itemRepo,
+		userRepo:         userRepo,
+		adminToken:       s.AdminToken,
+		strictCategories: s.StrictCategories,
+		maxUploadBytes:   s.MaxUploadBytes,
+		metrics:          metrics,
+	}
 
 	// set up routes
 	mux := http.NewServeMux()
-	mux.HandleFunc("GET /", h.Hello)
+	mux.HandleFunc("GET /{$}", h.Hello)
+	mux.HandleFunc("GET /healthz", h.Healthz)
+	mux.HandleFunc("GET /readyz", h.Readyz)
+	mux.Handle("GET /metrics", metrics.Handler())
 	mux.HandleFunc("POST /items", h.AddItem)
 	mux.HandleFunc("GET /items", h.GetItem)
 	mux.HandleFunc("GET /images/{filename}", h.GetImage)
 	mux.HandleFunc("GET /items/{id}", h.GetItemByID)
+	mux.HandleFunc("PATCH /items/{id}", h.UpdateItem)
+	mux.HandleFunc("DELETE /items/{id}", h.DeleteItem)
+	mux.HandleFunc("POST /items/{id}/restore", h.RestoreItem)
+	mux.HandleFunc("POST /items/{id}/status", h.UpdateItemStatus)
+	mux.HandleFunc("POST /items/{id}/purchase", h.Purchase)
+	mux.HandleFunc("GET /categories", h.GetCategories)
+	mux.HandleFunc("POST /categories", h.AddCategory)
+	mux.HandleFunc("PATCH /categories/{id}", h.RenameCategory)
+	mux.HandleFunc("POST /categories/{id}/merge", h.MergeCategory)
+	mux.HandleFunc("GET /search", h.Search)
+	mux.HandleFunc("GET /users/{id}/items", h.GetUserItems)
+	mux.HandleFunc("GET /orders", h.GetOrders)
+	mux.HandleFunc("POST /auth/signup", h.Signup)
+	mux.HandleFunc("POST /auth/login", h.Login)
+	mux.HandleFunc("POST /auth/logout", h.Logout)
+
+	srv := &http.Server{
+		Handler:           accessLogMiddleware(corsMiddleware(authMiddleware(metricsMiddleware(mux, metrics), userRepo), mux, s.cors())),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		ErrorLog:          slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	ln, err := net.Listen("tcp", ":"+s.Port)
+	if err != nil {
+		slog.Error("failed to start server: ", "error", err)
+		return 1
+	}
 
 	// start the server
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// delete images no item uses in the background until the server stops
+	gc := &imageCollector{repo: itemRepo, store: imgStore, grace: s.ImageGCGracePeriod, metrics: metrics}
+	gcDone := make(chan struct{})
+	go func() {
+		defer close(gcDone)
+		gc.run(ctx, s.ImageGCInterval)
+	}()
+	// wait for the collector before the repository is closed
+	defer func() {
+		stop()
+		<-gcDone
+	}()
+
 	slog.Info("http server started on", "port", s.Port)
-	err = http.ListenAndServe(":"+s.Port, simpleCORSMiddleware(simpleLoggerMiddleware(mux), frontURL, []string{"GET", "HEAD", "POST", "OPTIONS"}))
+	err = serve(ctx, srv, ln, shutdownTimeout)
 	if err != nil {
-		slog.Error("failed to start server: ", "error", err)
+		slog.Error("failed to serve: ", "error", err)
 		return 1
 	}
+	slog.Info("http server stopped")
 
 	return 0
 }
 
+// serve serves HTTP requests on ln until ctx is done,
+// and then shuts srv down, waiting up to timeout for in-flight requests to finish.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, timeout time.Duration) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down http server", "timeout", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// close the remaining connections once the deadline passes
+		srv.Close()
+		return fmt.Errorf("failed to shut down gracefully: %w", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
 type Handlers struct {
-	// imgDirPath is the path to the directory storing images.
-	imgDirPath string
-	itemRepo   ItemRepository
+	// imgStore stores the uploaded images.
+	imgStore ImageStore
+	// tmpDir is the directory for uploads being received. The default temporary directory is used if empty.
+	tmpDir   string
+	itemRepo ItemRepository
+	// userRepo stores the users and their sessions.
+	userRepo UserRepository
+	// adminToken is the token admin requests send in the X-Admin-Token header.
+	// Admin-only features are disabled when it is empty.
+	adminToken string
+	// strictCategories makes adding or updating items fail for unknown categories
+	// instead of creating them.
+	strictCategories bool
+	// maxUploadBytes is the maximum size of a request uploading an image. 0 means no limit.
+	maxUploadBytes int64
+	// metrics records application metrics. It may be nil.
+	metrics *Metrics
+}
+
+// limitUpload limits the size of the request body to maxUploadBytes.
+func (s *Handlers) limitUpload(w http.ResponseWriter, r *http.Request) {
+	if s.maxUploadBytes > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxUploadBytes)
+	}
+}
+
+// isAdmin reports whether the request is sent by an admin.
+func (s *Handlers) isAdmin(r *http.Request) bool {
+	token := r.Header.Get("X-Admin-Token")
+	if s.adminToken == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
 }
 
 type HelloResponse struct {
@@ -89,7 +292,9 @@ func (s *Handlers) Hello(w http.ResponseWriter, r *http.Request) {
 type AddItemRequest struct {
 	Name string 		`form:"name"`
 	Category string `form:"category"` // STEP 4-2: add a category field
-	Image []byte 		`form:"image"` // STEP 4-4: add an image field
+	Image *imageUpload `form:"image"` // STEP 4-4: add an image field
+	Price int64 `form:"price"` // in the minor units of Currency
+	Currency string `form:"currency"`
 }
 
 type AddItemResponse struct {
@@ -97,70 +302,94 @@ type AddItemResponse struct {
 }
 
 // parseAddItemRequest parses and validates the request to add an item.
-func parseAddItemRequest(r *http.Request) (*AddItemRequest, error) {
-	req := &AddItemRequest{
-		Name: r.FormValue("name"),
-		Category: r.FormValue("category"),
-		// Image: []byte(r.FormValue("image")),
+// The uploaded image is saved to a temporary file in dir.
+func parseAddItemRequest(r *http.Request, dir string) (req *AddItemRequest, err error) {
+	fields, image, err := parseItemForm(r, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			image.discard()
+		}
+	}()
+	req = &AddItemRequest{
+		Name: fields.Get("name"),
+		Category: fields.Get("category"),
 	}
 
 	// validate the request
-	if req.Name == "" {
-		return nil, errors.New("name is required")
+	if err := validateItemName(req.Name); err != nil {
+		return nil, err
 	}
 
 	// STEP 4-2: validate the category field
-	if req.Category == "" {
-		return nil, errors.New("category is required")
+	if err := validateItemCategory(req.Category); err != nil {
+		return nil, err
 	}
 
-	// STEP 4-4: validate the image field
-	file, header, err := r.FormFile("image")
+	// validate the price and currency fields
+	if fields.Get("price") == "" {
+		return nil, errors.New("price is required")
+	}
+	req.Price, err = parsePrice(fields.Get("price"))
 	if err != nil {
-		return nil, errors.New("image is required")
+		return nil, err
+	}
+	req.Currency, err = parseCurrency(fields.Get("currency"))
+	if err != nil {
+		return nil, err
 	}
-	defer file.Close()
-	// if req.Image == nil {
-	// 	return nil, errors.New("image is required")
-	// }
 
-	// // get the image path name
-	// imagePath := string(req.Image)
+	// STEP 4-4: validate the image field
+	if image == nil {
+		return nil, errors.New("image is required")
+	}
 
-	// STEP 4-4: validate the image file name
-	if !strings.HasSuffix(header.Filename, ".jpg") {
-		return nil, errors.New("image file must be a .jpg")
-	} 
+	req.Image = image
+	return req, nil
+}
 
-	// STEP 4-4: validate the image file
-	// if _, err := os.Stat(imagePath); os.IsNotExist(err) {
-	// 	return nil, errors.New("image file does not exist")
-	// } else if err != nil {
-	// 	return nil, errors.New("failed to check image file")
-	// }
-	imageData, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read image file: %w", err)
+// validateItemName validates the name of an item.
+func validateItemName(name string) error {
+	if name == "" {
+		return errors.New("name is required")
 	}
+	return nil
+}
 
-	req.Image = imageData
-	return req, nil
+// validateItemCategory validates the category name of an item.
+func validateItemCategory(category string) error {
+	if category == "" {
+		return errors.New("category is required")
+	}
+	return nil
 }
 
 // AddItem is a handler to add a new item for POST /items .
+// The logged-in user is the seller of the item.
 func (s *Handlers) AddItem(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	seller := currentUser(w, r)
+	if seller == nil {
+		return
+	}
+	s.limitUpload(w, r)
 
-	req, err := parseAddItemRequest(r)
+	req, err := parseAddItemRequest(r, s.tmpDir)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		http.Error(w, err.Error(), requestErrorStatus(err))
+		return
+	}
+	defer req.Image.discard()
+	if !s.checkCategory(w, r, req.Category) {
 		return
 	}
 
 	// STEP 4-4: uncomment on adding an implementation to store an image
-	fileName, err := s.storeImage(req.Image)
+	fileName, err := s.storeItemImage(ctx, req.Image)
 	if err != nil {
-		slog.Error("failed to store image: ", "error", err)
+		slog.ErrorContext(r.Context(), "failed to store image: ", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -169,17 +398,21 @@ func (s *Handlers) AddItem(w http.ResponseWriter, r *http.Request) {
 		Name: req.Name,
 		Category: req.Category,
 		ImageName: fileName,
+		SellerID: seller.ID,
+		Price: req.Price,
+		Currency: req.Currency,
 	}
-	message := fmt.Sprintf("item received: %s, category received: %s, image name received: %s" , item.Name, item.Category, item.ImageName)
-	slog.Info(message)
+	message := fmt.Sprintf("item received: %s, category received: %s, image name received: %s, price received: %d %s" , item.Name, item.Category, item.ImageName, item.Price, item.Currency)
+	slog.InfoContext(r.Context(), message)
 
 	// store an item in the db
 	err = s.itemRepo.Insert(ctx, item)
 	if err != nil {
-		slog.Error("failed to store item: ", "error", err)
+		slog.ErrorContext(r.Context(), "failed to store item: ", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	s.metrics.itemCreated(item.Category)
 
 	resp := AddItemResponse{Message: message}
 	err = json.NewEncoder(w).Encode(resp)
@@ -194,61 +427,353 @@ type GetItemResponse struct {
 	// make sure to change the field name to "items"
 	// the field name should be the same as the JSON response
 	Items []Item `json:"items"`
+	// NextCursor is passed as ?cursor= to fetch the next page.
+	// It is empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
-// AddItem is a handler to add a new item for GET /items .
+const (
+	// defaultItemsLimit is the page size used when ?limit= is not specified.
+	defaultItemsLimit = 50
+	// maxItemsLimit is the largest page size a client may request.
+	maxItemsLimit = 100
+)
+
+type GetItemsRequest struct {
+	Limit          int         // query parameter
+	After          *Item       // decoded from the cursor query parameter
+	CategoryID     int         // query parameter
+	Category       string      // query parameter
+	NamePrefix     string      // query parameter
+	Currency       string      // query parameter
+	MinPrice       *int64      // query parameter
+	MaxPrice       *int64      // query parameter
+	Status         ItemStatus  // query parameter
+	IncludeDeleted bool        // query parameter
+	Sort           ItemSortKey // query parameter
+	Order          SortOrder   // query parameter
+}
+
+// parseGetItemsRequest parses and validates the request to list items.
+func parseGetItemsRequest(r *http.Request) (*GetItemsRequest, error) {
+	query := r.URL.Query()
+	req := &GetItemsRequest{
+		Limit:      defaultItemsLimit,
+		Category:   query.Get("category"),
+		NamePrefix: query.Get("name_prefix"),
+		Sort:       SortByID,
+		Order:      OrderAsc,
+	}
+
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > maxItemsLimit {
+			return nil, fmt.Errorf("limit must be an integer between 1 and %d", maxItemsLimit)
+		}
+		req.Limit = limit
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		req.After = after
+	}
+
+	if includeDeleted := query.Get("include_deleted"); includeDeleted != "" {
+		deleted, err := strconv.ParseBool(includeDeleted)
+		if err != nil {
+			return nil, errors.New("include_deleted must be true or false")
+		}
+		req.IncludeDeleted = deleted
+	}
+
+	if categoryIDStr := query.Get("category_id"); categoryIDStr != "" {
+		categoryID, err := strconv.Atoi(categoryIDStr)
+		if err != nil || categoryID < 1 {
+			return nil, errors.New("category_id must be a positive integer")
+		}
+		req.CategoryID = categoryID
+	}
+
+	// validate the price range
+	if currency := query.Get("currency"); currency != "" {
+		currency, err := parseCurrency(currency)
+		if err != nil {
+			return nil, err
+		}
+		req.Currency = currency
+	}
+	var err error
+	if req.MinPrice, err = parsePriceParam(query, "min_price"); err != nil {
+		return nil, err
+	}
+	if req.MaxPrice, err = parsePriceParam(query, "max_price"); err != nil {
+		return nil, err
+	}
+	if req.MinPrice != nil && req.MaxPrice != nil && *req.MinPrice > *req.MaxPrice {
+		return nil, errors.New("min_price must not be greater than max_price")
+	}
+
+	if status := query.Get("status"); status != "" {
+		if req.Status, err = parseItemStatus(status); err != nil {
+			return nil, err
+		}
+	}
+
+	// validate the sort key and order
+	if sort := query.Get("sort"); sort != "" {
+		switch ItemSortKey(sort) {
+		case SortByID, SortByCreatedAt:
+			req.Sort = ItemSortKey(sort)
+		default:
+			return nil, fmt.Errorf("unknown sort key %q: must be one of %s, %s", sort, SortByID, SortByCreatedAt)
+		}
+	}
+	if order := query.Get("order"); order != "" {
+		switch SortOrder(order) {
+		case OrderAsc, OrderDesc:
+			req.Order = SortOrder(order)
+		default:
+			return nil, fmt.Errorf("unknown order %q: must be one of %s, %s", order, OrderAsc, OrderDesc)
+		}
+	}
+
+	return req, nil
+}
+
+// itemQuery returns the query listing the items req asks for.
+func (req *GetItemsRequest) itemQuery() ItemQuery {
+	return ItemQuery{
+		CategoryID:     req.CategoryID,
+		Category:       req.Category,
+		NamePrefix:     req.NamePrefix,
+		Currency:       req.Currency,
+		MinPrice:       req.MinPrice,
+		MaxPrice:       req.MaxPrice,
+		Status:         req.Status,
+		IncludeDeleted: req.IncludeDeleted,
+		Sort:           req.Sort,
+		Order:          req.Order,
+		After:          req.After,
+		Limit:          req.Limit,
+	}
+}
+
+// itemCursor is the position of the last item on a page.
+// It holds every key items can be sorted by.
+type itemCursor struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// encodeCursor encodes the last item on a page as an opaque cursor.
+func encodeCursor(item Item) string {
+	b, _ := json.Marshal(itemCursor{ID: item.ID, CreatedAt: item.CreatedAt})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor decodes a cursor created by encodeCursor.
+func decodeCursor(cursor string) (*Item, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	var c itemCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID < 1 {
+		return nil, errors.New("invalid cursor")
+	}
+	return &Item{ID: c.ID, CreatedAt: c.CreatedAt}, nil
+}
+
+// GetItem is a handler to return a page of items for GET /items .
+// It responds 304 Not Modified if the page is unchanged since the client's copy.
 func (s *Handlers) GetItem(w http.ResponseWriter, r *http.Request) {
-	items, err := s.itemRepo.GetItems()
+	ctx := r.Context()
+
+	req, err := parseGetItemsRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.IncludeDeleted && !s.isAdmin(r) {
+		writeJSONError(w, http.StatusForbidden, "include_deleted is only allowed for admins")
+		return
+	}
+
+	items, hasNext, err := s.itemRepo.ListItems(ctx, req.itemQuery())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 	resp := GetItemResponse{Items: items}
-	err = json.NewEncoder(w).Encode(resp)
+	if hasNext {
+		resp.NextCursor = encodeCursor(items[len(items)-1])
+	}
+	writeJSONWithETag(w, r, resp)
+}
+
+// GetUserItemsResponse is a response for GET /users/{id}/items .
+type GetUserItemsResponse struct {
+	// User is the seller of the items.
+	User *User `json:"user"`
+	GetItemResponse
+}
+
+// GetUserItems is a handler to return a page of the items a user sells for GET /users/{id}/items .
+// It takes the same query parameters as GET /items.
+func (s *Handlers) GetUserItems(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := parsePathID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := parseGetItemsRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.IncludeDeleted && !s.isAdmin(r) {
+		writeJSONError(w, http.StatusForbidden, "include_deleted is only allowed for admins")
+		return
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("user %d not found", id))
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	q := req.itemQuery()
+	q.SellerID = user.ID
+	items, hasNext, err := s.itemRepo.ListItems(ctx, q)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	resp := GetUserItemsResponse{User: user, GetItemResponse: GetItemResponse{Items: items}}
+	if hasNext {
+		resp.NextCursor = encodeCursor(items[len(items)-1])
+	}
+	writeJSONWithETag(w, r, resp)
 }
 
-// storeImage stores an image and returns the file path and an error if any.
-// this method calculates the hash sum of the image as a file name to avoid the duplication of a same file
-// and stores it in the image directory.
-func (s *Handlers) storeImage(image []byte) (filePath string, err error) {
-	// STEP 4-4: add an implementation to store an image
-	// check if image is empty
-	if len(image) == 0 {
-		return "", errors.New("image is empty")
+type SearchRequest struct {
+	Keyword string // query parameter
+}
+
+// parseSearchRequest parses and validates the request to search items.
+func parseSearchRequest(r *http.Request) (*SearchRequest, error) {
+	req := &SearchRequest{
+		Keyword: strings.TrimSpace(r.URL.Query().Get("keyword")),
 	}
 
-	// calc hash sum (this is [32]byte)
-	hash := sha256.Sum256(image)
-	// convert hash to hexadecimal string
-	hashHex := hex.EncodeToString(hash[:]) // 16進数のファイル名に変換
-	// add extension to the file name
-	ext := ".jpg"
-	fileName := hashHex + ext
-	// build image save path
-	savePath := filepath.Join(s.imgDirPath, fileName)
+	// validate the request
+	if req.Keyword == "" {
+		return nil, errors.New("keyword is required")
+	}
+
+	return req, nil
+}
+
+// Search is a handler to search items by keyword for GET /search .
+// Items are ordered by relevance to the keyword.
+func (s *Handlers) Search(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, err := parseSearchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	items, err := s.itemRepo.Search(ctx, req.Keyword)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to search items: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	resp := GetItemResponse{Items: items}
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// imageURLDir is the path under which GET /images/{filename} serves the images.
+// Image names of items are relative to the API root, e.g. images/<hash>.jpg.
+const imageURLDir = "images"
+
+// defaultImageName is the image returned for images that are not found.
+const defaultImageName = "default.jpg"
+
+// storeUpload puts an uploaded image into the image store under its content-addressed name
+// and returns its path. The name is the SHA-256 of the content, so an image uploaded twice is stored once.
+// If overwrite is true, the image is stored even if it already exists.
+func (s *Handlers) storeUpload(ctx context.Context, upload *imageUpload, overwrite bool) (filePath string, err error) {
+	// STEP 4-4: add an implementation to store an image
+	defer upload.discard()
+	name := upload.FileName()
+	filePath = path.Join(imageURLDir, name)
 
 	// check if the image already exists
-	_, err = os.Stat(savePath)
-	if err == nil {
-		return savePath, nil
+	if !overwrite {
+		exists, err := s.imgStore.Exists(ctx, name)
+		if err != nil {
+			return "", fmt.Errorf("failed to check image: %w", err)
+		}
+		if exists {
+			s.metrics.imageDeduplicated()
+			return filePath, nil
+		}
 	}
 
 	// store image
-	err = os.WriteFile(savePath, image, 0644)
+	f, err := os.Open(upload.TempPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open uploaded image: %w", err)
+	}
+	defer f.Close()
+	err = s.imgStore.Put(ctx, name, f, upload.Size, upload.Format.ContentType)
 	if err != nil {
 		return "", fmt.Errorf("failed to save image: %w", err)
 	}
+	s.metrics.imageStored(upload.Size)
+	generateThumbnails(ctx, s.imgStore, name)
 
 	// return the image file path
-	return savePath, nil
+	return filePath, nil
+}
+
+// storeItemImage records an uploaded image in the repository and stores it,
+// so that the item referring to it keeps it from being garbage collected.
+// The image is recorded first: an existing record keeps the collector from deleting the file,
+// and a new record means the collector may have just deleted it, so the file is stored again.
+func (s *Handlers) storeItemImage(ctx context.Context, upload *imageUpload) (filePath string, err error) {
+	created, err := s.itemRepo.SaveImage(ctx, &Image{
+		Name:        upload.FileName(),
+		Hash:        upload.Hash,
+		Size:        upload.Size,
+		ContentType: upload.Format.ContentType,
+	})
+	if err != nil {
+		return "", err
+	}
+	return s.storeUpload(ctx, upload, created)
 }
 
 type GetImageRequest struct {
 	FileName string // path value
+	// Width is the requested width in pixels, or 0 for the original image.
+	Width int // query parameter "w"
 }
 
 // parseGetImageRequest parses and validates the request to get an image.
@@ -261,39 +786,104 @@ func parseGetImageRequest(r *http.Request) (*GetImageRequest, error) {
 	if req.FileName == "" {
 		return nil, errors.New("filename is required")
 	}
+	// to prevent directory traversal attacks
+	if err := validateImageName(req.FileName); err != nil {
+		return nil, err
+	}
+	// validate the image suffix
+	if _, ok := imageFormatByExt(req.FileName); !ok {
+		return nil, fmt.Errorf("image name does not end with a supported image extension: %s", req.FileName)
+	}
+	if w := r.URL.Query().Get("w"); w != "" {
+		width, err := strconv.Atoi(w)
+		if err != nil || width < 1 {
+			return nil, fmt.Errorf("w must be a positive integer: %q", w)
+		}
+		req.Width = width
+	}
 
 	return req, nil
 }
 
 // GetImage is a handler to return an image for GET /images/{filename} .
 // If the specified image is not found, it returns the default image.
+// With ?w=, it returns the smallest resized variant at least that wide.
+// Content-addressed images are cached as immutable, and the default image only briefly.
 func (s *Handlers) GetImage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
 	req, err := parseGetImageRequest(r)
 	if err != nil {
-		slog.Warn("failed to parse get image request: ", "error", err)
+		slog.WarnContext(ctx, "failed to parse get image request: ", "error", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	imgPath, err := s.buildImagePath(req.FileName)
+	name := req.FileName
+	// fallback is set when another image is served in place of the requested one
+	fallback := false
+	exists, err := s.imgStore.Exists(ctx, name)
 	if err != nil {
-		if !errors.Is(err, errImageNotFound) {
-			slog.Warn("failed to build image path: ", "error", err)
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
+		slog.ErrorContext(ctx, "failed to check image: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !exists {
+		// when the image is not found, it returns the default image without an error.
+		slog.DebugContext(ctx, "image not found", "filename", name)
+		name = defaultImageName
+		fallback = true
+	}
+
+	// serve the closest resized variant if a width is requested
+	if width, ok := thumbnailWidth(req.Width); req.Width > 0 && ok {
+		thumbName, err := ensureThumbnail(ctx, s.imgStore, name, width)
+		if err != nil {
+			// the original image can still be shown, only larger than needed
+			slog.WarnContext(ctx, "failed to get thumbnail: ", "name", name, "width", width, "error", err)
+			fallback = true
+		} else {
+			name = thumbName
 		}
+	}
 
-		// when the image is not found, it returns the default image without an error.
-		slog.Debug("image not found", "filename", imgPath)
-		imgPath = filepath.Join(s.imgDirPath, "default.jpg")
+	rc, info, err := s.imgStore.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, errImageNotFound) {
+			writeJSONError(w, http.StatusNotFound, "image not found")
+			return
+		}
+		slog.ErrorContext(ctx, "failed to get image: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+	defer rc.Close()
 
-	slog.Info("returned image", "path", imgPath)
-	http.ServeFile(w, r, imgPath)
+	slog.InfoContext(ctx, "returned image", "name", name)
+	w.Header().Set("Content-Type", imageContentType(name))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	setImageCacheHeaders(w.Header(), name, fallback)
+	if rs, ok := rc.(io.ReadSeeker); ok {
+		// supports range and conditional requests
+		http.ServeContent(w, r, name, info.ModTime, rs)
+		return
+	}
+	if etag := w.Header().Get("ETag"); etag != "" && etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	if _, err := io.Copy(w, rc); err != nil {
+		slog.WarnContext(ctx, "failed to write image: ", "error", err)
+	}
 }
 
 // parseGetItemByID parses and validates the request to get an item by id.
 func parseGetItemByID(r *http.Request) (int, error) {
+	return parsePathID(r)
+}
+
+// parsePathID parses and validates the {id} path value.
+func parsePathID(r *http.Request) (int, error) {
 	idStr := r.PathValue("id")
 
 	// validate the request
@@ -302,57 +892,510 @@ func parseGetItemByID(r *http.Request) (int, error) {
 	}
 
 	// convert the id to an integer
-	return strconv.Atoi(idStr)
+	id, err := strconv.Atoi(idStr)
+	if err != nil || id < 1 {
+		return -1, errors.New("id must be a positive integer")
+	}
+	return id, nil
 }
 
 // GetItemByID is a handler to return an item by id for GET /items/{id} .
+// It responds 304 Not Modified if the item is unchanged since the client's copy.
 func (s *Handlers) GetItemByID(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
 	// parse the request
 	id, err := parseGetItemByID(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	// get items
-	items, err := s.itemRepo.GetItems()
+	// get the item
+	item, err := s.itemRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, errItemNotFound) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("item %d not found", id))
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// return the item, or 304 if the client has it already
+	writeJSONWithETag(w, r, item)
+}
+
+type UpdateItemRequest struct {
+	ID       int          // path value
+	Name     *string      `form:"name" json:"name"`
+	Category *string      `form:"category" json:"category"`
+	Image    *imageUpload `form:"image" json:"-"` // base64 encoded in JSON
+	Price    *int64       `form:"price" json:"price"`
+	Currency *string      `form:"currency" json:"currency"`
+}
+
+// parseUpdateItemRequest parses and validates the request to update an item.
+// The body is either multipart form data or JSON, and every field is optional.
+// In JSON, the image is base64 encoded. The uploaded image is saved to a temporary file in dir.
+func parseUpdateItemRequest(r *http.Request, dir string) (*UpdateItemRequest, error) {
+	id, err := parseGetItemByID(r)
+	if err != nil {
+		return nil, err
+	}
+	req := &UpdateItemRequest{ID: id}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		var body struct {
+			Name     *string `json:"name"`
+			Category *string `json:"category"`
+			Image    []byte  `json:"image"`
+			Price    *int64  `json:"price"`
+			Currency *string `json:"currency"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		req.Name, req.Category = body.Name, body.Category
+		req.Price, req.Currency = body.Price, body.Currency
+		if body.Image != nil {
+			req.Image, err = saveUpload(dir, bytes.NewReader(body.Image))
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		fields, image, err := parseItemForm(r, dir)
+		if err != nil {
+			return nil, err
+		}
+		req.Image = image
+		if values, ok := fields["name"]; ok {
+			req.Name = &values[0]
+		}
+		if values, ok := fields["category"]; ok {
+			req.Category = &values[0]
+		}
+		if values, ok := fields["price"]; ok {
+			price, err := parsePrice(values[0])
+			if err != nil {
+				req.Image.discard()
+				return nil, err
+			}
+			req.Price = &price
+		}
+		if values, ok := fields["currency"]; ok {
+			req.Currency = &values[0]
+		}
+	}
+
+	// validate the request
+	if err := validateUpdateItemRequest(req); err != nil {
+		req.Image.discard()
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// validateUpdateItemRequest validates the fields of a request to update an item.
+func validateUpdateItemRequest(req *UpdateItemRequest) error {
+	if req.Name == nil && req.Category == nil && req.Image == nil && req.Price == nil && req.Currency == nil {
+		return errors.New("at least one of name, category, image, price or currency is required")
+	}
+	if req.Name != nil {
+		if err := validateItemName(*req.Name); err != nil {
+			return err
+		}
+	}
+	if req.Category != nil {
+		if err := validateItemCategory(*req.Category); err != nil {
+			return err
+		}
+	}
+	if req.Price != nil {
+		if err := validatePrice(*req.Price); err != nil {
+			return err
+		}
+	}
+	if req.Currency != nil {
+		currency, err := parseCurrency(*req.Currency)
+		if err != nil {
+			return err
+		}
+		req.Currency = &currency
+	}
+	return nil
+}
+
+// UpdateItem is a handler to update an item for PATCH /items/{id} .
+// Fields that are not specified are left unchanged. Only the seller can update the item.
+func (s *Handlers) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := currentUser(w, r)
+	if user == nil {
+		return
+	}
+	s.limitUpload(w, r)
+
+	req, err := parseUpdateItemRequest(r, s.tmpDir)
+	if err != nil {
+		http.Error(w, err.Error(), requestErrorStatus(err))
+		return
+	}
+	defer req.Image.discard()
+	if req.Category != nil && !s.checkCategory(w, r, *req.Category) {
+		return
+	}
+
+	item, err := s.itemRepo.GetByID(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, errItemNotFound) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("item %d not found", req.ID))
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !checkSeller(w, user, item.ID, item.SellerID) {
+		return
+	}
+
+	if req.Name != nil {
+		item.Name = *req.Name
+	}
+	if req.Category != nil {
+		item.Category = *req.Category
+	}
+	if req.Price != nil {
+		item.Price = *req.Price
+	}
+	if req.Currency != nil {
+		item.Currency = *req.Currency
+	}
+	if req.Image != nil {
+		fileName, err := s.storeItemImage(ctx, req.Image)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to store image: ", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		item.ImageName = fileName
+	}
+
+	err = s.itemRepo.Update(ctx, item)
+	if err != nil {
+		if errors.Is(err, errItemNotFound) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("item %d not found", req.ID))
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to update item: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// respond with the stored item, since the category may be stored under an existing name in another case
+	item, err = s.itemRepo.GetByID(ctx, item.ID)
+	if err != nil {
+		if errors.Is(err, errItemNotFound) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("item %d not found", req.ID))
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to get item: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(item)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// DeleteItem is a handler to soft-delete an item for DELETE /items/{id} .
+// Only the seller can delete the item.
+func (s *Handlers) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := currentUser(w, r)
+	if user == nil {
+		return
+	}
+
+	id, err := parseGetItemByID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.authorizeSeller(w, r, user, id) {
+		return
+	}
+
+	err = s.itemRepo.Delete(ctx, id)
 	if err != nil {
+		if errors.Is(err, errItemNotFound) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("item %d not found", id))
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to delete item: ", "error", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	// validate the id
-	if id < 0 || id >= len(items) {
-		http.Error(w, "Index out of range", http.StatusNotFound)
+	slog.InfoContext(r.Context(), "item deleted", "id", id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RestoreItem is a handler to restore a soft-deleted item for POST /items/{id}/restore .
+// It returns the restored item. Only the seller can restore the item.
+func (s *Handlers) RestoreItem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := currentUser(w, r)
+	if user == nil {
+		return
+	}
+
+	id, err := parseGetItemByID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !s.authorizeSeller(w, r, user, id) {
+		return
+	}
+
+	err = s.itemRepo.Restore(ctx, id)
+	if err != nil {
+		if errors.Is(err, errItemNotFound) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("deleted item %d not found", id))
+			return
+		}
+		slog.ErrorContext(r.Context(), "failed to restore item: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	slog.InfoContext(r.Context(), "item restored", "id", id)
+
+	item, err := s.itemRepo.GetByID(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	// return the item
 	w.Header().Set("Content-Type", "application/json")
-	err = json.NewEncoder(w).Encode(items[id])
+	err = json.NewEncoder(w).Encode(item)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 }
 
-// buildImagePath builds the image path and validates it.
-func (s *Handlers) buildImagePath(imageFileName string) (string, error) {
-	imgPath := filepath.Join(s.imgDirPath, filepath.Clean(imageFileName))
+// authorizeSeller writes an error response and returns false
+// if the item with the given id does not exist or user is not its seller.
+// Soft-deleted items are authorized as well, so that their sellers can restore them.
+func (s *Handlers) authorizeSeller(w http.ResponseWriter, r *http.Request, user *User, id int) bool {
+	sellerID, err := s.itemRepo.GetSellerID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, errItemNotFound) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("item %d not found", id))
+			return false
+		}
+		slog.ErrorContext(r.Context(), "failed to get seller: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return checkSeller(w, user, id, sellerID)
+}
 
-	// to prevent directory traversal attacks
-	rel, err := filepath.Rel(s.imgDirPath, imgPath)
-	if err != nil || strings.HasPrefix(rel, "..") {
-		return "", fmt.Errorf("invalid image path: %s", imgPath)
+// checkSeller writes a 403 response and returns false if user is not the seller of the item.
+// Items without a seller cannot be changed by anyone.
+func checkSeller(w http.ResponseWriter, user *User, itemID, sellerID int) bool {
+	if sellerID != user.ID {
+		writeJSONError(w, http.StatusForbidden, fmt.Sprintf("only the seller can change item %d", itemID))
+		return false
 	}
+	return true
+}
 
-	// validate the image suffix
-	if !strings.HasSuffix(imgPath, ".jpg") && !strings.HasSuffix(imgPath, ".jpeg") {
-		return "", fmt.Errorf("image path does not end with .jpg or .jpeg: %s", imgPath)
+// checkCategory writes a 400 response and returns false
+// if categories are strict and the category does not exist.
+func (s *Handlers) checkCategory(w http.ResponseWriter, r *http.Request, category string) bool {
+	if !s.strictCategories {
+		return true
 	}
+	_, err := s.itemRepo.GetCategoryByName(r.Context(), category)
+	if err != nil {
+		if errors.Is(err, errCategoryNotFound) {
+			http.Error(w, fmt.Sprintf("unknown category: %s", category), http.StatusBadRequest)
+			return false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
 
-	// check if the image exists
-	_, err = os.Stat(imgPath)
+type GetCategoriesResponse struct {
+	Categories []Category `json:"categories"`
+}
+
+// GetCategories is a handler to return all categories with their item counts for GET /categories .
+func (s *Handlers) GetCategories(w http.ResponseWriter, r *http.Request) {
+	categories, err := s.itemRepo.ListCategories(r.Context())
 	if err != nil {
-		return imgPath, errImageNotFound
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, GetCategoriesResponse{Categories: categories})
+}
+
+type AddCategoryRequest struct {
+	Name string `form:"name"`
+}
+
+// parseAddCategoryRequest parses and validates the request to add or rename a category.
+func parseAddCategoryRequest(r *http.Request) (*AddCategoryRequest, error) {
+	req := &AddCategoryRequest{
+		Name: strings.TrimSpace(r.FormValue("name")),
 	}
 
-	return imgPath, nil
+	// validate the request
+	if err := validateItemCategory(req.Name); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// AddCategory is a handler to add a new category for POST /categories .
+// Only admins can add categories.
+func (s *Handlers) AddCategory(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		writeJSONError(w, http.StatusForbidden, "only admins can change categories")
+		return
+	}
+	req, err := parseAddCategoryRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	category, err := s.itemRepo.CreateCategory(r.Context(), req.Name)
+	if err != nil {
+		writeCategoryError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "category created", "id", category.ID, "name", category.Name)
+	writeJSON(w, r, http.StatusCreated, category)
+}
+
+// RenameCategory is a handler to rename a category for PATCH /categories/{id} .
+// Only admins can rename categories.
+func (s *Handlers) RenameCategory(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		writeJSONError(w, http.StatusForbidden, "only admins can change categories")
+		return
+	}
+	id, err := parsePathID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req, err := parseAddCategoryRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	category, err := s.itemRepo.RenameCategory(r.Context(), id, req.Name)
+	if err != nil {
+		writeCategoryError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "category renamed", "id", category.ID, "name", category.Name)
+	writeJSON(w, r, http.StatusOK, category)
+}
+
+type MergeCategoryRequest struct {
+	ID   int // path value
+	Into int `form:"into"`
+}
+
+// parseMergeCategoryRequest parses and validates the request to merge a category into another.
+func parseMergeCategoryRequest(r *http.Request) (*MergeCategoryRequest, error) {
+	id, err := parsePathID(r)
+	if err != nil {
+		return nil, err
+	}
+	req := &MergeCategoryRequest{ID: id}
+
+	// validate the request
+	into, err := strconv.Atoi(r.FormValue("into"))
+	if err != nil || into < 1 {
+		return nil, errors.New("into must be the id of the category to merge into")
+	}
+	if into == id {
+		return nil, errors.New("cannot merge a category into itself")
+	}
+	req.Into = into
+
+	return req, nil
+}
+
+// MergeCategory is a handler to move every item of a category into another category
+// and delete it for POST /categories/{id}/merge . It returns the merged category.
+// Only admins can merge categories.
+func (s *Handlers) MergeCategory(w http.ResponseWriter, r *http.Request) {
+	if !s.isAdmin(r) {
+		writeJSONError(w, http.StatusForbidden, "only admins can change categories")
+		return
+	}
+	req, err := parseMergeCategoryRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	category, err := s.itemRepo.MergeCategories(r.Context(), req.ID, req.Into)
+	if err != nil {
+		writeCategoryError(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "category merged", "from", req.ID, "into", category.ID)
+	writeJSON(w, r, http.StatusOK, category)
+}
+
+// writeCategoryError writes a response for an error returned by the category methods of ItemRepository.
+func writeCategoryError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, errCategoryNotFound):
+		writeJSONError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, errCategoryExists):
+		writeJSONError(w, http.StatusConflict, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "failed to manage category: ", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// writeJSON writes resp as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, resp any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	err := json.NewEncoder(w).Encode(resp)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to encode response: ", "error", err)
+	}
+}
+
+// ErrorResponse is a JSON response body for errors.
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
+
+// writeJSONError writes an error response with a JSON body.
+func writeJSONError(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(ErrorResponse{Message: message})
 }
@@ -1,17 +1,27 @@
 package app
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"io"
+	"mime/multipart"
+	"net"
 	"net/http"
 	"net/http/httptest"
-	"net/url"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"go.uber.org/mock/gomock"
 )
 
@@ -23,6 +33,9 @@ func TestParseAddItemRequest(t *testing.T) {
 		err bool
 	}
 
+	defaultImage := readTestImage(t, "../images/default.jpg")
+	defaultHash := sha256.Sum256(defaultImage)
+
 	// STEP 6-1: define test cases
 	cases := map[string]struct {
 		args map[string]string
@@ -32,17 +45,73 @@ func TestParseAddItemRequest(t *testing.T) {
 			args: map[string]string{
 				"name":     "jacket", 
 				"category": "fashion", 
-				"image": 		"../images/dummy.jpg", 
+				"image": 		"../images/default.jpg", 
+				"price":    "1500",
 			},
 			wants: wants{
 				req: &AddItemRequest{
 					Name: "jacket",
 					Category: "fashion", 
-					Image: []byte("../images/dummy.jpg"),
+					Image: &imageUpload{
+						Hash:   hex.EncodeToString(defaultHash[:]),
+						Size:   int64(len(defaultImage)),
+						Format: imageFormat{ContentType: "image/jpeg", Ext: ".jpg"},
+					},
+					Price:    1500,
+					Currency: "JPY",
 				},
 				err: false,
 			},
 		},
+		"ok: price in another currency": {
+			args: map[string]string{
+				"name":     "jacket",
+				"category": "fashion",
+				"image":    "../images/default.jpg",
+				"price":    "1999",
+				"currency": "usd",
+			},
+			wants: wants{
+				req: &AddItemRequest{
+					Name:     "jacket",
+					Category: "fashion",
+					Image: &imageUpload{
+						Hash:   hex.EncodeToString(defaultHash[:]),
+						Size:   int64(len(defaultImage)),
+						Format: imageFormat{ContentType: "image/jpeg", Ext: ".jpg"},
+					},
+					Price:    1999,
+					Currency: "USD",
+				},
+			},
+		},
+		"ng: no price": {
+			args: map[string]string{
+				"name":     "jacket",
+				"category": "fashion",
+				"image":    "../images/default.jpg",
+			},
+			wants: wants{err: true},
+		},
+		"ng: negative price": {
+			args: map[string]string{
+				"name":     "jacket",
+				"category": "fashion",
+				"image":    "../images/default.jpg",
+				"price":    "-1",
+			},
+			wants: wants{err: true},
+		},
+		"ng: unsupported currency": {
+			args: map[string]string{
+				"name":     "jacket",
+				"category": "fashion",
+				"image":    "../images/default.jpg",
+				"price":    "1500",
+				"currency": "BTC",
+			},
+			wants: wants{err: true},
+		},
 		"ng: empty request": {
 			args: map[string]string{},
 			wants: wants{
@@ -56,21 +125,11 @@ func TestParseAddItemRequest(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			t.Parallel()
 
-			// prepare request body
-			values := url.Values{}
-			for k, v := range tt.args {
-				values.Set(k, v)
-			}
-
 			// prepare HTTP request
-			req, err := http.NewRequest("POST", "http://localhost:9001/items", strings.NewReader(values.Encode()))
-			if err != nil {
-				t.Fatalf("failed to create request: %v", err)
-			}
-			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req := newAddItemRequest(t, tt.args)
 
 			// execute test target
-			got, err := parseAddItemRequest(req)
+			got, err := parseAddItemRequest(req, t.TempDir())
 
 			// confirm the result
 			if err != nil {
@@ -79,7 +138,8 @@ func TestParseAddItemRequest(t *testing.T) {
 				}
 				return
 			}
-			if diff := cmp.Diff(tt.wants.req, got); diff != "" {
+			defer got.Image.discard()
+			if diff := cmp.Diff(tt.wants.req, got, cmpopts.IgnoreFields(imageUpload{}, "TempPath")); diff != "" {
 				t.Errorf("unexpected request (-want +got):\n%s", diff)
 			}
 		})
@@ -130,20 +190,29 @@ func TestAddItem(t *testing.T) {
 		code int
 	}
 	cases := map[string]struct {
-		args     map[string]string
-		injector func(m *MockItemRepository)
+		args      map[string]string
+		strict    bool
+		anonymous bool
+		injector  func(m *MockItemRepository)
 		wants
 	}{
 		"ok: correctly inserted": {
 			args: map[string]string{
 				"name":     "used iPhone 16e",
 				"category": "phone",
-				"image": 		"../images/dummy.jpg",
+				"image": 		"../images/default.jpg",
+				"price":    "1500",
 			},
 			injector: func(m *MockItemRepository) {
 				// STEP 6-3: define mock expectation
 				// succeeded to insert
-				m.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil)
+				m.EXPECT().SaveImage(gomock.Any(), gomock.Any()).Return(true, nil)
+				m.EXPECT().Insert(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, item *Item) error {
+					if item.SellerID != 1 {
+						t.Errorf("expected seller 1, got %d", item.SellerID)
+					}
+					return nil
+				})
 			},
 			wants: wants{
 				code: http.StatusOK,
@@ -153,17 +222,78 @@ func TestAddItem(t *testing.T) {
 			args: map[string]string{
 				"name":     "used iPhone 16e",
 				"category": "phone",
-				"image": 		"../images/dummy.jpg",
+				"image": 		"../images/default.jpg",
+				"price":    "1500",
 			},
 			injector: func(m *MockItemRepository) {
 				// STEP 6-3: define mock expectation
 				// failed to insert
+				m.EXPECT().SaveImage(gomock.Any(), gomock.Any()).Return(true, nil)
 				m.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(errors.New("failed to insert"))
 			},
 			wants: wants{
 				code: http.StatusInternalServerError,
 			},
 		},
+		"ng: failed to save image": {
+			args: map[string]string{
+				"name":     "used iPhone 16e",
+				"category": "phone",
+				"image":    "../images/default.jpg",
+				"price":    "1500",
+			},
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().SaveImage(gomock.Any(), gomock.Any()).Return(false, errors.New("failed to save image"))
+			},
+			wants: wants{
+				code: http.StatusInternalServerError,
+			},
+		},
+		"ok: known category with strict categories": {
+			args: map[string]string{
+				"name":     "used iPhone 16e",
+				"category": "phone",
+				"image":    "../images/default.jpg",
+				"price":    "1500",
+			},
+			strict: true,
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetCategoryByName(gomock.Any(), "phone").Return(&Category{ID: 1, Name: "phone"}, nil)
+				m.EXPECT().SaveImage(gomock.Any(), gomock.Any()).Return(true, nil)
+				m.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+			},
+		},
+		"ng: unknown category with strict categories": {
+			args: map[string]string{
+				"name":     "used iPhone 16e",
+				"category": "phon",
+				"image":    "../images/default.jpg",
+				"price":    "1500",
+			},
+			strict: true,
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetCategoryByName(gomock.Any(), "phon").Return(nil, errCategoryNotFound)
+			},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: not logged in": {
+			args: map[string]string{
+				"name":     "used iPhone 16e",
+				"category": "phone",
+				"image":    "../images/default.jpg",
+				"price":    "1500",
+			},
+			anonymous: true,
+			injector:  func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusUnauthorized,
+			},
+		},
 	}
 
 	for name, tt := range cases {
@@ -175,15 +305,14 @@ func TestAddItem(t *testing.T) {
 			mockIR := NewMockItemRepository(ctrl)
 			tt.injector(mockIR)
 			h := &Handlers{
-				imgDirPath: "../images/",
+				imgStore: NewMemoryImageStore(),
 				itemRepo: mockIR,
+				strictCategories: tt.strict,
 			}
-			values := url.Values{}
-			for k, v := range tt.args {
-				values.Set(k, v)
+			req := newAddItemRequest(t, tt.args)
+			if !tt.anonymous {
+				req = withUser(req, &User{ID: 1, Name: "taro"})
 			}
-			req := httptest.NewRequest("POST", "/items", strings.NewReader(values.Encode()))
-			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 
 			rr := httptest.NewRecorder()
 			h.AddItem(rr, req)
@@ -194,10 +323,7 @@ func TestAddItem(t *testing.T) {
 			if tt.wants.code >= 400 {
 				return
 			}
-			hashedPath, err := h.storeImage([]byte(tt.args["image"]))
-			if err != nil {
-				t.Errorf("failed to store image: ")
-			}
+			hashedPath := storeTestImage(t, h, readTestImage(t, tt.args["image"]))
 			expected := tt.args
 			expected["image"] = hashedPath
 			for _, v := range expected {
@@ -225,6 +351,11 @@ func TestAddItemE2e(t *testing.T) {
 		}
 	})
 
+	seller, err := (&userRepository{db: db}).CreateUser(context.Background(), "taro", "hash")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
 	type wants struct {
 		code int
 	}
@@ -236,7 +367,8 @@ func TestAddItemE2e(t *testing.T) {
 			args: map[string]string{
 				"name":     "used iPhone 16e",
 				"category": "phone",
-				"image": 		"../images/dummy.jpg",
+				"image": 		"../images/default.jpg",
+				"price":    "1500",
 			},
 			wants: wants{
 				code: http.StatusOK,
@@ -246,7 +378,8 @@ func TestAddItemE2e(t *testing.T) {
 			args: map[string]string{
 				"name":     "",
 				"category": "phone",
-				"image": 		"../images/dummy.jpg",
+				"image": 		"../images/default.jpg",
+				"price":    "1500",
 			},
 			wants: wants{
 				code: http.StatusBadRequest,
@@ -257,16 +390,11 @@ func TestAddItemE2e(t *testing.T) {
 	for name, tt := range cases {
 		t.Run(name, func(t *testing.T) {
 			h := &Handlers{
-				imgDirPath: "../images/",
+				imgStore: NewMemoryImageStore(),
 				itemRepo: &itemRepository{db: db},
 			}
 
-			values := url.Values{}
-			for k, v := range tt.args {
-				values.Set(k, v)
-			}
-			req := httptest.NewRequest("POST", "/items", strings.NewReader(values.Encode()))
-			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req := withUser(newAddItemRequest(t, tt.args), seller)
 
 			rr := httptest.NewRecorder()
 			h.AddItem(rr, req)
@@ -280,10 +408,7 @@ func TestAddItemE2e(t *testing.T) {
 			}
 
 			// check response body
-			hashedPath, err := h.storeImage([]byte(tt.args["image"]))
-			if err != nil {
-				t.Errorf("failed to store image: ")
-			}
+			hashedPath := storeTestImage(t, h, readTestImage(t, tt.args["image"]))
 			expected := tt.args
 			expected["image"] = hashedPath
 			for _, v := range expected {
@@ -295,11 +420,11 @@ func TestAddItemE2e(t *testing.T) {
 			// STEP 6-4: check inserted data
 			var gotItem Item
 			err = db.QueryRow(`
-				SELECT i.name, c.name AS category, i.image_name
+				SELECT i.name, c.name AS category, i.image_name, i.seller_id
 				FROM items i
 				JOIN categories c ON i.category_id = c.id
 				WHERE i.name = ?`, tt.args["name"]).
-				Scan(&gotItem.Name, &gotItem.Category, &gotItem.ImageName)
+				Scan(&gotItem.Name, &gotItem.Category, &gotItem.ImageName, &gotItem.SellerID)
 			if err != nil {
 				t.Fatalf("failed to fetch item from database: %v", err)
 			}
@@ -313,51 +438,1605 @@ func TestAddItemE2e(t *testing.T) {
 			if gotItem.ImageName != expected["image"] {
 				t.Errorf("expected image name %s, got %s", expected["image"], gotItem.ImageName)
 			}
+			if gotItem.SellerID != seller.ID {
+				t.Errorf("expected seller %d, got %d", seller.ID, gotItem.SellerID)
+			}
 		})
 	}
 }
 
-func setupDB(t *testing.T) (db *sql.DB, closers []func(), e error) {
-	t.Helper()
+func TestGetItem(t *testing.T) {
+	t.Parallel()
 
-	defer func() {
-		if e != nil {
-			for _, c := range closers {
-				c()
+	items := []Item{
+		{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg"},
+		{ID: 2, Name: "used iPhone 16e", Category: "phone", ImageName: "default.jpg"},
+	}
+
+	type wants struct {
+		code int
+		resp GetItemResponse
+	}
+	cases := map[string]struct {
+		query    string
+		admin    bool
+		injector func(m *MockItemRepository)
+		wants
+	}{
+		"ok: first page with next cursor": {
+			query: "?limit=2",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListItems(gomock.Any(), ItemQuery{Sort: SortByID, Order: OrderAsc, Limit: 2}).Return(items, true, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				resp: GetItemResponse{Items: items, NextCursor: encodeCursor(items[1])},
+			},
+		},
+		"ok: last page without next cursor": {
+			query: "?cursor=" + encodeCursor(items[1]),
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListItems(gomock.Any(), ItemQuery{Sort: SortByID, Order: OrderAsc, After: &Item{ID: 2}, Limit: defaultItemsLimit}).Return(items[:1], false, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				resp: GetItemResponse{Items: items[:1]},
+			},
+		},
+		"ok: filtered and sorted": {
+			query: "?category=fashion&category_id=3&name_prefix=jac&sort=created_at&order=desc",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListItems(gomock.Any(), ItemQuery{
+					CategoryID: 3,
+					Category:   "fashion",
+					NamePrefix: "jac",
+					Sort:       SortByCreatedAt,
+					Order:      OrderDesc,
+					Limit:      defaultItemsLimit,
+				}).Return(items[:1], false, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				resp: GetItemResponse{Items: items[:1]},
+			},
+		},
+		"ok: price range": {
+			query: "?currency=jpy&min_price=1000&max_price=5000",
+			injector: func(m *MockItemRepository) {
+				minPrice, maxPrice := int64(1000), int64(5000)
+				m.EXPECT().ListItems(gomock.Any(), ItemQuery{
+					Currency: "JPY",
+					MinPrice: &minPrice,
+					MaxPrice: &maxPrice,
+					Sort:     SortByID,
+					Order:    OrderAsc,
+					Limit:    defaultItemsLimit,
+				}).Return(items[:1], false, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				resp: GetItemResponse{Items: items[:1]},
+			},
+		},
+		"ok: by status": {
+			query: "?status=on_sale",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListItems(gomock.Any(), ItemQuery{Status: StatusOnSale, Sort: SortByID, Order: OrderAsc, Limit: defaultItemsLimit}).Return(items, false, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				resp: GetItemResponse{Items: items},
+			},
+		},
+		"ng: unknown status": {
+			query:    "?status=shipped",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: min price above max price": {
+			query:    "?min_price=5000&max_price=1000",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: price with a fraction": {
+			query:    "?max_price=19.99",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: unsupported currency": {
+			query:    "?currency=BTC",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: unknown sort key": {
+			query:    "?sort=price",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: unknown order": {
+			query:    "?order=up",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: invalid category id": {
+			query:    "?category_id=fashion",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ok: deleted items for admins": {
+			query: "?include_deleted=true",
+			admin: true,
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListItems(gomock.Any(), ItemQuery{IncludeDeleted: true, Sort: SortByID, Order: OrderAsc, Limit: defaultItemsLimit}).Return(items, false, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				resp: GetItemResponse{Items: items},
+			},
+		},
+		"ng: deleted items for non-admins": {
+			query:    "?include_deleted=true",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusForbidden,
+			},
+		},
+		"ng: limit out of range": {
+			query:    "?limit=0",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: invalid cursor": {
+			query:    "?cursor=not-a-cursor",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: failed to list": {
+			query: "",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListItems(gomock.Any(), gomock.Any()).Return(nil, false, errors.New("failed to list"))
+			},
+			wants: wants{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			h := &Handlers{itemRepo: mockIR, adminToken: "admin-token"}
+
+			req := httptest.NewRequest("GET", "/items"+tt.query, nil)
+			if tt.admin {
+				req.Header.Set("X-Admin-Token", "admin-token")
 			}
-		}
-	}()
+			rr := httptest.NewRecorder()
+			h.GetItem(rr, req)
 
-	// create a temporary file for e2e testing
-	f, err := os.CreateTemp(".", "*.sqlite3")
-	if err != nil {
-		return nil, nil, err
+			if tt.wants.code != rr.Code {
+				t.Errorf("expected status code %d, got %d", tt.wants.code, rr.Code)
+			}
+			if tt.wants.code >= 400 {
+				return
+			}
+
+			var got GetItemResponse
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if diff := cmp.Diff(tt.wants.resp, got); diff != "" {
+				t.Errorf("unexpected response body (-want +got):\n%s", diff)
+			}
+		})
 	}
-	closers = append(closers, func() {
-		f.Close()
-		os.Remove(f.Name())
-	})
+}
 
-	// set up tables
-	db, err = sql.Open("sqlite3", f.Name())
+func TestListItemsE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
 	if err != nil {
-		return nil, nil, err
+		t.Fatalf("failed to set up database: %v", err)
 	}
-	closers = append(closers, func() {
-		db.Close()
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
 	})
 
-	// read the schema
-	schemaBytes, err := os.ReadFile("../db/items.sql")
+	repo := &itemRepository{db: db}
+	ctx := context.Background()
+	for _, item := range []Item{
+		{Name: "jacket", Category: "fashion", Price: 5000, Currency: "JPY"},
+		{Name: "used iPhone 16e", Category: "phone", Price: 80000, Currency: "JPY"},
+		{Name: "jeans", Category: "fashion", Price: 3000, Currency: "JPY"},
+		{Name: "used iPhone 15", Category: "phone", Price: 49999, Currency: "USD"},
+		{Name: "jersey", Category: "fashion", Price: 0, Currency: "JPY"},
+	} {
+		item.ImageName = "default.jpg"
+		if err := repo.Insert(ctx, &item); err != nil {
+			t.Fatalf("failed to insert item: %v", err)
+		}
+	}
+	// spread the creation times so that they differ from the id order
+	_, err = db.Exec("UPDATE items SET created_at = datetime('2025-01-01', '+' || ((id * 2) % 5) || ' days')")
 	if err != nil {
-			return nil, nil, err
+		t.Fatalf("failed to update created_at: %v", err)
 	}
 
-	// create the table
-	_, err = db.Exec(string(schemaBytes))
-	if err != nil {
-		return nil, nil, err
+	minPrice, maxPrice, free, phonePrice := int64(3000), int64(5000), int64(0), int64(40000)
+	cases := map[string]struct {
+		query ItemQuery
+		want  []string
+	}{
+		"ok: all items by id": {
+			query: ItemQuery{},
+			want:  []string{"jacket", "used iPhone 16e", "jeans", "used iPhone 15", "jersey"},
+		},
+		"ok: newest first": {
+			query: ItemQuery{Sort: SortByCreatedAt, Order: OrderDesc},
+			want:  []string{"used iPhone 16e", "used iPhone 15", "jacket", "jeans", "jersey"},
+		},
+		"ok: by id descending": {
+			query: ItemQuery{Order: OrderDesc},
+			want:  []string{"jersey", "used iPhone 15", "jeans", "used iPhone 16e", "jacket"},
+		},
+		"ok: by category name": {
+			query: ItemQuery{Category: "phone"},
+			want:  []string{"used iPhone 16e", "used iPhone 15"},
+		},
+		"ok: by category name ignoring case": {
+			query: ItemQuery{Category: "Phone"},
+			want:  []string{"used iPhone 16e", "used iPhone 15"},
+		},
+		"ok: by category id": {
+			query: ItemQuery{CategoryID: 1},
+			want:  []string{"jacket", "jeans", "jersey"},
+		},
+		"ok: by name prefix": {
+			query: ItemQuery{NamePrefix: "je", Sort: SortByCreatedAt},
+			want:  []string{"jersey", "jeans"},
+		},
+		"ok: by price range": {
+			query: ItemQuery{Currency: "JPY", MinPrice: &minPrice, MaxPrice: &maxPrice},
+			want:  []string{"jacket", "jeans"},
+		},
+		"ok: free items": {
+			query: ItemQuery{MaxPrice: &free},
+			want:  []string{"jersey"},
+		},
+		"ok: by currency": {
+			query: ItemQuery{Currency: "USD"},
+			want:  []string{"used iPhone 15"},
+		},
+		"ok: price range in the default currency": {
+			query: ItemQuery{MinPrice: &phonePrice},
+			want:  []string{"used iPhone 16e"},
+		},
+		"ok: price range in another currency": {
+			query: ItemQuery{Currency: "USD", MinPrice: &phonePrice},
+			want:  []string{"used iPhone 15"},
+		},
 	}
 
-	return db, closers, nil
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			// walk through every page and collect the item names
+			var got []string
+			q := tt.query
+			q.Limit = 2
+			for {
+				items, hasNext, err := repo.ListItems(ctx, q)
+				if err != nil {
+					t.Fatalf("failed to list items: %v", err)
+				}
+				if len(items) > q.Limit {
+					t.Fatalf("expected at most %d items, got %d", q.Limit, len(items))
+				}
+				for _, item := range items {
+					got = append(got, item.Name)
+				}
+				if !hasNext {
+					break
+				}
+				q.After = &items[len(items)-1]
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected items (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetItemByID(t *testing.T) {
+	t.Parallel()
+
+	item := &Item{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg"}
+
+	type wants struct {
+		code int
+		body any
+	}
+	cases := map[string]struct {
+		id       string
+		injector func(m *MockItemRepository)
+		wants
+	}{
+		"ok: item found": {
+			id: "1",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(item, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				body: item,
+			},
+		},
+		"ng: item not found": {
+			id: "2",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 2).Return(nil, errItemNotFound)
+			},
+			wants: wants{
+				code: http.StatusNotFound,
+				body: &ErrorResponse{Message: "item 2 not found"},
+			},
+		},
+		"ng: invalid id": {
+			id:       "abc",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: failed to get": {
+			id: "1",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(nil, errors.New("failed to get"))
+			},
+			wants: wants{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			h := &Handlers{itemRepo: mockIR}
+
+			req := httptest.NewRequest("GET", "/items/"+tt.id, nil)
+			req.SetPathValue("id", tt.id)
+			rr := httptest.NewRecorder()
+			h.GetItemByID(rr, req)
+
+			if tt.wants.code != rr.Code {
+				t.Errorf("expected status code %d, got %d", tt.wants.code, rr.Code)
+			}
+			if tt.wants.body == nil {
+				return
+			}
+
+			// decode the body into a value of the same type as the expected one
+			got := reflect.New(reflect.TypeOf(tt.wants.body).Elem()).Interface()
+			if err := json.NewDecoder(rr.Body).Decode(got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if diff := cmp.Diff(tt.wants.body, got); diff != "" {
+				t.Errorf("unexpected response body (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetByIDE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	repo := &itemRepository{db: db}
+	ctx := context.Background()
+	for _, name := range []string{"jacket", "jeans"} {
+		if err := repo.Insert(ctx, &Item{Name: name, Category: "fashion", ImageName: "default.jpg"}); err != nil {
+			t.Fatalf("failed to insert item: %v", err)
+		}
+	}
+
+	got, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if got.ID != 1 || got.Name != "jacket" || got.Category != "fashion" {
+		t.Errorf("unexpected item: %+v", got)
+	}
+
+	_, err = repo.GetByID(ctx, 3)
+	if !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected errItemNotFound, got %v", err)
+	}
+}
+
+func TestUserItemsE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	userRepo := &userRepository{db: db}
+	repo := &itemRepository{db: db}
+	ctx := context.Background()
+	var sellers []*User
+	for _, name := range []string{"taro", "hanako"} {
+		user, err := userRepo.CreateUser(ctx, name, "hash")
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		sellers = append(sellers, user)
+	}
+	for _, item := range []Item{
+		{Name: "jacket", Category: "fashion", SellerID: sellers[0].ID},
+		{Name: "jeans", Category: "fashion", SellerID: sellers[1].ID},
+		{Name: "jersey", Category: "fashion", SellerID: sellers[0].ID},
+		{Name: "old jumper", Category: "fashion"},
+	} {
+		item.ImageName = "default.jpg"
+		if err := repo.Insert(ctx, &item); err != nil {
+			t.Fatalf("failed to insert item: %v", err)
+		}
+	}
+	if err := repo.Delete(ctx, 3); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+
+	// the seller of a deleted item is still known, so that they can restore it
+	for id, want := range map[int]int{1: sellers[0].ID, 3: sellers[0].ID, 4: 0} {
+		got, err := repo.GetSellerID(ctx, id)
+		if err != nil || got != want {
+			t.Errorf("GetSellerID(%d) = %d, %v, want %d", id, got, err, want)
+		}
+	}
+	if _, err := repo.GetSellerID(ctx, 5); !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected errItemNotFound, got %v", err)
+	}
+
+	h := &Handlers{itemRepo: repo, userRepo: userRepo}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /users/{id}/items", h.GetUserItems)
+	cases := map[string]struct {
+		path  string
+		code  int
+		items []string
+	}{
+		"ok: items of a seller": {
+			path:  "/users/1/items",
+			code:  http.StatusOK,
+			items: []string{"jacket"},
+		},
+		"ok: items of another seller": {
+			path:  "/users/2/items?order=desc",
+			code:  http.StatusOK,
+			items: []string{"jeans"},
+		},
+		"ng: unknown user": {
+			path: "/users/3/items",
+			code: http.StatusNotFound,
+		},
+		"ng: invalid id": {
+			path: "/users/taro/items",
+			code: http.StatusBadRequest,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest("GET", tt.path, nil))
+
+			if res.Code != tt.code {
+				t.Fatalf("expected status code %d, got %d: %s", tt.code, res.Code, res.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var resp GetUserItemsResponse
+			if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			var names []string
+			for _, item := range resp.Items {
+				names = append(names, item.Name)
+				if item.SellerID != resp.User.ID {
+					t.Errorf("expected seller %d, got %d", resp.User.ID, item.SellerID)
+				}
+			}
+			if diff := cmp.Diff(tt.items, names); diff != "" {
+				t.Errorf("unexpected items (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateItem(t *testing.T) {
+	t.Parallel()
+
+	newItem := func() *Item {
+		return &Item{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg", SellerID: 1}
+	}
+
+	type wants struct {
+		code int
+		item *Item
+	}
+	cases := map[string]struct {
+		contentType string
+		body        string
+		user        *User
+		injector    func(m *MockItemRepository)
+		wants
+	}{
+		"ok: rename with JSON": {
+			contentType: "application/json",
+			body:        `{"name": "denim jacket"}`,
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(newItem(), nil)
+				m.EXPECT().Update(gomock.Any(), &Item{ID: 1, Name: "denim jacket", Category: "fashion", ImageName: "default.jpg", SellerID: 1}).Return(nil)
+				// the stored item is returned
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(&Item{ID: 1, Name: "denim jacket", Category: "fashion", ImageName: "default.jpg", SellerID: 1}, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				item: &Item{ID: 1, Name: "denim jacket", Category: "fashion", ImageName: "default.jpg", SellerID: 1},
+			},
+		},
+		"ok: change category with a form": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        "category=outer",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(newItem(), nil)
+				m.EXPECT().Update(gomock.Any(), &Item{ID: 1, Name: "jacket", Category: "outer", ImageName: "default.jpg", SellerID: 1}).Return(nil)
+				// the stored item is returned
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(&Item{ID: 1, Name: "jacket", Category: "outer", ImageName: "default.jpg", SellerID: 1}, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				item: &Item{ID: 1, Name: "jacket", Category: "outer", ImageName: "default.jpg", SellerID: 1},
+			},
+		},
+		"ok: change price with JSON": {
+			contentType: "application/json",
+			body:        `{"price": 1999, "currency": "usd"}`,
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(newItem(), nil)
+				m.EXPECT().Update(gomock.Any(), &Item{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg", SellerID: 1, Price: 1999, Currency: "USD"}).Return(nil)
+				// the stored item is returned
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(&Item{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg", SellerID: 1, Price: 1999, Currency: "USD"}, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				item: &Item{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg", SellerID: 1, Price: 1999, Currency: "USD"},
+			},
+		},
+		"ok: change category to an existing one in another case": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        "category=FASHION",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(newItem(), nil)
+				m.EXPECT().Update(gomock.Any(), &Item{ID: 1, Name: "jacket", Category: "FASHION", ImageName: "default.jpg", SellerID: 1}).Return(nil)
+				// the item is stored in the existing category
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(newItem(), nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				item: &Item{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg", SellerID: 1},
+			},
+		},
+		"ng: price too high": {
+			contentType: "application/x-www-form-urlencoded",
+			body:        "price=10000000",
+			injector:    func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: not the seller": {
+			contentType: "application/json",
+			body:        `{"name": "denim jacket"}`,
+			user:        &User{ID: 2, Name: "jiro"},
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(newItem(), nil)
+			},
+			wants: wants{
+				code: http.StatusForbidden,
+			},
+		},
+		"ng: item without a seller": {
+			contentType: "application/json",
+			body:        `{"name": "denim jacket"}`,
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(&Item{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg"}, nil)
+			},
+			wants: wants{
+				code: http.StatusForbidden,
+			},
+		},
+		"ng: nothing to update": {
+			contentType: "application/json",
+			body:        `{}`,
+			injector:    func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: empty name": {
+			contentType: "application/json",
+			body:        `{"name": ""}`,
+			injector:    func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: item not found": {
+			contentType: "application/json",
+			body:        `{"name": "denim jacket"}`,
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(nil, errItemNotFound)
+			},
+			wants: wants{
+				code: http.StatusNotFound,
+			},
+		},
+		"ng: failed to update": {
+			contentType: "application/json",
+			body:        `{"name": "denim jacket"}`,
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(newItem(), nil)
+				m.EXPECT().Update(gomock.Any(), gomock.Any()).Return(errors.New("failed to update"))
+			},
+			wants: wants{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			h := &Handlers{itemRepo: mockIR}
+
+			user := tt.user
+			if user == nil {
+				user = &User{ID: 1, Name: "taro"}
+			}
+			req := httptest.NewRequest("PATCH", "/items/1", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			req.SetPathValue("id", "1")
+			req = withUser(req, user)
+			rr := httptest.NewRecorder()
+			h.UpdateItem(rr, req)
+
+			if tt.wants.code != rr.Code {
+				t.Errorf("expected status code %d, got %d", tt.wants.code, rr.Code)
+			}
+			if tt.wants.code >= 400 {
+				return
+			}
+
+			var got Item
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if diff := cmp.Diff(tt.wants.item, &got); diff != "" {
+				t.Errorf("unexpected item (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	repo := &itemRepository{db: db}
+	ctx := context.Background()
+	if err := repo.Insert(ctx, &Item{Name: "jacket", Category: "fashon", ImageName: "default.jpg"}); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+
+	// fix a typo in the category
+	want := &Item{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg", Status: StatusOnSale}
+	if err := repo.Update(ctx, want); err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+	got, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(Item{}, "CreatedAt")); diff != "" {
+		t.Errorf("unexpected item (-want +got):\n%s", diff)
+	}
+
+	err = repo.Update(ctx, &Item{ID: 2, Name: "jeans", Category: "fashion", ImageName: "default.jpg"})
+	if !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected errItemNotFound, got %v", err)
+	}
+}
+
+func TestDeleteItem(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		method    string
+		anonymous bool
+		injector  func(m *MockItemRepository)
+		code      int
+	}{
+		"ok: deleted": {
+			method: "DELETE",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetSellerID(gomock.Any(), 1).Return(1, nil)
+				m.EXPECT().Delete(gomock.Any(), 1).Return(nil)
+			},
+			code: http.StatusNoContent,
+		},
+		"ng: delete deleted item": {
+			method: "DELETE",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetSellerID(gomock.Any(), 1).Return(1, nil)
+				m.EXPECT().Delete(gomock.Any(), 1).Return(errItemNotFound)
+			},
+			code: http.StatusNotFound,
+		},
+		"ng: delete missing item": {
+			method: "DELETE",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetSellerID(gomock.Any(), 1).Return(0, errItemNotFound)
+			},
+			code: http.StatusNotFound,
+		},
+		"ng: delete item of another seller": {
+			method: "DELETE",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetSellerID(gomock.Any(), 1).Return(2, nil)
+			},
+			code: http.StatusForbidden,
+		},
+		"ng: delete without login": {
+			method:    "DELETE",
+			anonymous: true,
+			injector:  func(m *MockItemRepository) {},
+			code:      http.StatusUnauthorized,
+		},
+		"ok: restored": {
+			method: "POST",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetSellerID(gomock.Any(), 1).Return(1, nil)
+				m.EXPECT().Restore(gomock.Any(), 1).Return(nil)
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(&Item{ID: 1, Name: "jacket", Category: "fashion", SellerID: 1}, nil)
+			},
+			code: http.StatusOK,
+		},
+		"ng: restore item that is not deleted": {
+			method: "POST",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetSellerID(gomock.Any(), 1).Return(1, nil)
+				m.EXPECT().Restore(gomock.Any(), 1).Return(errItemNotFound)
+			},
+			code: http.StatusNotFound,
+		},
+		"ng: restore item of another seller": {
+			method: "POST",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetSellerID(gomock.Any(), 1).Return(2, nil)
+			},
+			code: http.StatusForbidden,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			h := &Handlers{itemRepo: mockIR}
+
+			req := httptest.NewRequest(tt.method, "/items/1", nil)
+			req.SetPathValue("id", "1")
+			if !tt.anonymous {
+				req = withUser(req, &User{ID: 1, Name: "taro"})
+			}
+			rr := httptest.NewRecorder()
+			if tt.method == "DELETE" {
+				h.DeleteItem(rr, req)
+			} else {
+				h.RestoreItem(rr, req)
+			}
+
+			if tt.code != rr.Code {
+				t.Errorf("expected status code %d, got %d", tt.code, rr.Code)
+			}
+		})
+	}
+}
+
+func TestSoftDeleteE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	fts, err := hasFTS5(db)
+	if err != nil {
+		t.Fatalf("failed to check FTS5 support: %v", err)
+	}
+	repo := &itemRepository{db: db, fts: fts}
+	ctx := context.Background()
+	for _, name := range []string{"denim jacket", "leather jacket"} {
+		if err := repo.Insert(ctx, &Item{Name: name, Category: "fashion", ImageName: "default.jpg"}); err != nil {
+			t.Fatalf("failed to insert item: %v", err)
+		}
+	}
+
+	if err := repo.Delete(ctx, 1); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	if err := repo.Delete(ctx, 1); !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected errItemNotFound deleting twice, got %v", err)
+	}
+
+	// deleted items are hidden by default
+	if _, err := repo.GetByID(ctx, 1); !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected errItemNotFound, got %v", err)
+	}
+	names := func(items []Item) []string {
+		var names []string
+		for _, item := range items {
+			names = append(names, item.Name)
+		}
+		return names
+	}
+	listed, _, err := repo.ListItems(ctx, ItemQuery{Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to list items: %v", err)
+	}
+	if diff := cmp.Diff([]string{"leather jacket"}, names(listed)); diff != "" {
+		t.Errorf("unexpected listed items (-want +got):\n%s", diff)
+	}
+	found, err := repo.Search(ctx, "jacket")
+	if err != nil {
+		t.Fatalf("failed to search items: %v", err)
+	}
+	if diff := cmp.Diff([]string{"leather jacket"}, names(found)); diff != "" {
+		t.Errorf("unexpected found items (-want +got):\n%s", diff)
+	}
+
+	// deleted items are listed on request
+	listed, _, err = repo.ListItems(ctx, ItemQuery{IncludeDeleted: true, Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to list items: %v", err)
+	}
+	if diff := cmp.Diff([]string{"denim jacket", "leather jacket"}, names(listed)); diff != "" {
+		t.Errorf("unexpected listed items (-want +got):\n%s", diff)
+	}
+	if listed[0].DeletedAt == nil {
+		t.Errorf("expected deleted_at to be set")
+	}
+
+	if err := repo.Restore(ctx, 1); err != nil {
+		t.Fatalf("failed to restore item: %v", err)
+	}
+	if err := repo.Restore(ctx, 2); !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected errItemNotFound restoring an item that is not deleted, got %v", err)
+	}
+	got, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get restored item: %v", err)
+	}
+	if got.DeletedAt != nil {
+		t.Errorf("expected deleted_at to be cleared, got %v", got.DeletedAt)
+	}
+}
+
+func TestCategoryHandlers(t *testing.T) {
+	t.Parallel()
+
+	fashion := &Category{ID: 1, Name: "fashion", ItemCount: 3}
+
+	type wants struct {
+		code int
+		body any
+	}
+	cases := map[string]struct {
+		method   string
+		id       string
+		form     string
+		nonAdmin bool
+		injector func(m *MockItemRepository)
+		wants
+	}{
+		"ok: list categories": {
+			method: "GET",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListCategories(gomock.Any()).Return([]Category{*fashion}, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				body: &GetCategoriesResponse{Categories: []Category{*fashion}},
+			},
+		},
+		"ok: create category": {
+			method: "POST",
+			form:   "name=phone",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().CreateCategory(gomock.Any(), "phone").Return(&Category{ID: 2, Name: "phone"}, nil)
+			},
+			wants: wants{
+				code: http.StatusCreated,
+				body: &Category{ID: 2, Name: "phone"},
+			},
+		},
+		"ng: create duplicated category": {
+			method: "POST",
+			form:   "name=Fashion",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().CreateCategory(gomock.Any(), "Fashion").Return(nil, errCategoryExists)
+			},
+			wants: wants{
+				code: http.StatusConflict,
+			},
+		},
+		"ng: create category without name": {
+			method:   "POST",
+			form:     "name=",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ok: rename category": {
+			method: "PATCH",
+			id:     "1",
+			form:   "name=Fashion",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().RenameCategory(gomock.Any(), 1, "Fashion").Return(&Category{ID: 1, Name: "Fashion", ItemCount: 3}, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				body: &Category{ID: 1, Name: "Fashion", ItemCount: 3},
+			},
+		},
+		"ng: rename missing category": {
+			method: "PATCH",
+			id:     "9",
+			form:   "name=Fashion",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().RenameCategory(gomock.Any(), 9, "Fashion").Return(nil, errCategoryNotFound)
+			},
+			wants: wants{
+				code: http.StatusNotFound,
+			},
+		},
+		"ok: merge category": {
+			method: "POST",
+			id:     "2",
+			form:   "into=1",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().MergeCategories(gomock.Any(), 2, 1).Return(fashion, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				body: fashion,
+			},
+		},
+		"ng: merge category into itself": {
+			method:   "POST",
+			id:       "1",
+			form:     "into=1",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ok: list categories for non-admins": {
+			method:   "GET",
+			nonAdmin: true,
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().ListCategories(gomock.Any()).Return([]Category{*fashion}, nil)
+			},
+			wants: wants{
+				code: http.StatusOK,
+				body: &GetCategoriesResponse{Categories: []Category{*fashion}},
+			},
+		},
+		"ng: create category for non-admins": {
+			method:   "POST",
+			form:     "name=phone",
+			nonAdmin: true,
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusForbidden,
+			},
+		},
+		"ng: rename category for non-admins": {
+			method:   "PATCH",
+			id:       "1",
+			form:     "name=Fashion",
+			nonAdmin: true,
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusForbidden,
+			},
+		},
+		"ng: merge category for non-admins": {
+			method:   "POST",
+			id:       "2",
+			form:     "into=1",
+			nonAdmin: true,
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusForbidden,
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			h := &Handlers{itemRepo: mockIR, adminToken: "admin-token"}
+
+			req := httptest.NewRequest(tt.method, "/categories", strings.NewReader(tt.form))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if !tt.nonAdmin {
+				req.Header.Set("X-Admin-Token", "admin-token")
+			}
+			req.SetPathValue("id", tt.id)
+			rr := httptest.NewRecorder()
+			switch {
+			case tt.method == "GET":
+				h.GetCategories(rr, req)
+			case tt.method == "PATCH":
+				h.RenameCategory(rr, req)
+			case tt.id != "":
+				h.MergeCategory(rr, req)
+			default:
+				h.AddCategory(rr, req)
+			}
+
+			if tt.wants.code != rr.Code {
+				t.Errorf("expected status code %d, got %d", tt.wants.code, rr.Code)
+			}
+			if tt.wants.body == nil {
+				return
+			}
+
+			got := reflect.New(reflect.TypeOf(tt.wants.body).Elem()).Interface()
+			if err := json.NewDecoder(rr.Body).Decode(got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if diff := cmp.Diff(tt.wants.body, got); diff != "" {
+				t.Errorf("unexpected response body (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCategoriesE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	repo := &itemRepository{db: db}
+	ctx := context.Background()
+	for _, item := range []Item{
+		{Name: "jacket", Category: "Fashion"},
+		{Name: "jeans", Category: "fashion"},
+		{Name: "jersey", Category: "fashon"},
+	} {
+		item.ImageName = "default.jpg"
+		if err := repo.Insert(ctx, &item); err != nil {
+			t.Fatalf("failed to insert item: %v", err)
+		}
+		// the item gets the name the category is stored under
+		if item.Name == "jeans" && item.Category != "Fashion" {
+			t.Errorf("expected category Fashion, got %s", item.Category)
+		}
+	}
+
+	// category names are compared ignoring case
+	if _, err := repo.CreateCategory(ctx, "FASHION"); !errors.Is(err, errCategoryExists) {
+		t.Errorf("expected errCategoryExists, got %v", err)
+	}
+	if _, err := repo.RenameCategory(ctx, 2, "fashion"); !errors.Is(err, errCategoryExists) {
+		t.Errorf("expected errCategoryExists, got %v", err)
+	}
+	renamed, err := repo.RenameCategory(ctx, 1, "fashion")
+	if err != nil {
+		t.Fatalf("failed to rename category: %v", err)
+	}
+	if diff := cmp.Diff(&Category{ID: 1, Name: "fashion", ItemCount: 2}, renamed); diff != "" {
+		t.Errorf("unexpected renamed category (-want +got):\n%s", diff)
+	}
+
+	merged, err := repo.MergeCategories(ctx, 2, 1)
+	if err != nil {
+		t.Fatalf("failed to merge categories: %v", err)
+	}
+	if diff := cmp.Diff(&Category{ID: 1, Name: "fashion", ItemCount: 3}, merged); diff != "" {
+		t.Errorf("unexpected merged category (-want +got):\n%s", diff)
+	}
+	if _, err := repo.MergeCategories(ctx, 2, 1); !errors.Is(err, errCategoryNotFound) {
+		t.Errorf("expected errCategoryNotFound merging a deleted category, got %v", err)
+	}
+
+	created, err := repo.CreateCategory(ctx, "phone")
+	if err != nil {
+		t.Fatalf("failed to create category: %v", err)
+	}
+	categories, err := repo.ListCategories(ctx)
+	if err != nil {
+		t.Fatalf("failed to list categories: %v", err)
+	}
+	want := []Category{
+		{ID: 1, Name: "fashion", ItemCount: 3},
+		{ID: created.ID, Name: "phone", ItemCount: 0},
+	}
+	if diff := cmp.Diff(want, categories); diff != "" {
+		t.Errorf("unexpected categories (-want +got):\n%s", diff)
+	}
+
+	item, err := repo.GetByID(ctx, 3)
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if item.Category != "fashion" {
+		t.Errorf("expected the merged item to be in fashion, got %s", item.Category)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	type wants struct {
+		code  int
+		items []Item
+	}
+	cases := map[string]struct {
+		keyword  string
+		injector func(m *MockItemRepository)
+		wants
+	}{
+		"ok: items found": {
+			keyword: "jacket",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Search(gomock.Any(), "jacket").Return([]Item{
+					{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg"},
+				}, nil)
+			},
+			wants: wants{
+				code:  http.StatusOK,
+				items: []Item{{ID: 1, Name: "jacket", Category: "fashion", ImageName: "default.jpg"}},
+			},
+		},
+		"ng: empty keyword": {
+			keyword:  "",
+			injector: func(m *MockItemRepository) {},
+			wants: wants{
+				code: http.StatusBadRequest,
+			},
+		},
+		"ng: failed to search": {
+			keyword: "jacket",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().Search(gomock.Any(), "jacket").Return(nil, errors.New("failed to search"))
+			},
+			wants: wants{
+				code: http.StatusInternalServerError,
+			},
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			h := &Handlers{itemRepo: mockIR}
+
+			req := httptest.NewRequest("GET", "/search?keyword="+tt.keyword, nil)
+			rr := httptest.NewRecorder()
+			h.Search(rr, req)
+
+			if tt.wants.code != rr.Code {
+				t.Errorf("expected status code %d, got %d", tt.wants.code, rr.Code)
+			}
+			if tt.wants.code >= 400 {
+				return
+			}
+
+			var got GetItemResponse
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if diff := cmp.Diff(tt.wants.items, got.Items); diff != "" {
+				t.Errorf("unexpected items (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSearchE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	fts, err := hasFTS5(db)
+	if err != nil {
+		t.Fatalf("failed to check FTS5 support: %v", err)
+	}
+	repo := &itemRepository{db: db, fts: fts}
+	ctx := context.Background()
+	for _, item := range []Item{
+		{Name: "denim jacket", Category: "fashion", ImageName: "default.jpg"},
+		{Name: "used iPhone 16e", Category: "phone", ImageName: "default.jpg"},
+		{Name: "leather jacket", Category: "fashion", ImageName: "default.jpg"},
+	} {
+		if err := repo.Insert(ctx, &item); err != nil {
+			t.Fatalf("failed to insert item: %v", err)
+		}
+	}
+
+	cases := map[string]struct {
+		query string
+		want  []string
+	}{
+		"ok: match by name":       {query: "jacket", want: []string{"denim jacket", "leather jacket"}},
+		"ok: match by category":   {query: "phone", want: []string{"used iPhone 16e"}},
+		"ok: match by prefix":     {query: "iph", want: []string{"used iPhone 16e"}},
+		"ok: match every keyword": {query: "leather fashion", want: []string{"leather jacket"}},
+		"ok: no match":            {query: "camera", want: nil},
+		"ok: syntax is quoted":    {query: `"jacket OR`, want: nil},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			items, err := repo.Search(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("failed to search: %v", err)
+			}
+			var got []string
+			for _, item := range items {
+				got = append(got, item.Name)
+			}
+			if diff := cmp.Diff(tt.want, got, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("unexpected items (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSearchIndexAcrossBuildsE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	fts, err := hasFTS5(db)
+	if err != nil {
+		t.Fatalf("failed to check FTS5 support: %v", err)
+	}
+	repo := &itemRepository{db: db, fts: fts}
+	ctx := context.Background()
+	if err := repo.Insert(ctx, &Item{Name: "jacket", Category: "fashion", ImageName: "default.jpg"}); err != nil {
+		t.Fatalf("failed to insert item: %v", err)
+	}
+
+	if !fts {
+		// a trigger left by a build with FTS5 is dropped, so that items can still be written
+		_, err := db.Exec(`CREATE TRIGGER items_fts_after_insert AFTER INSERT ON items BEGIN
+			INSERT INTO items_fts (rowid, name) VALUES (new.id, new.name);
+		END`)
+		if err != nil {
+			t.Fatalf("failed to create trigger: %v", err)
+		}
+		if _, err := setupSchema(db); err != nil {
+			t.Fatalf("failed to set up schema: %v", err)
+		}
+		if err := repo.Insert(ctx, &Item{Name: "jeans", Category: "fashion", ImageName: "default.jpg"}); err != nil {
+			t.Errorf("failed to insert item: %v", err)
+		}
+		return
+	}
+
+	// a build without FTS5 drops the triggers, and items change meanwhile
+	triggers, err := searchIndexTriggers(db)
+	if err != nil {
+		t.Fatalf("failed to list triggers: %v", err)
+	}
+	for _, name := range triggers {
+		if _, err := db.Exec("DROP TRIGGER " + name); err != nil {
+			t.Fatalf("failed to drop trigger: %v", err)
+		}
+	}
+	if err := repo.Update(ctx, &Item{ID: 1, Name: "jeans", Category: "fashion", ImageName: "default.jpg"}); err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+
+	// the next build with FTS5 rebuilds the index
+	if _, err := setupSchema(db); err != nil {
+		t.Fatalf("failed to set up schema: %v", err)
+	}
+	for keyword, want := range map[string]int{"jacket": 0, "jeans": 1} {
+		items, err := repo.Search(ctx, keyword)
+		if err != nil {
+			t.Fatalf("failed to search items: %v", err)
+		}
+		if len(items) != want {
+			t.Errorf("expected %d items for %q, got %+v", want, keyword, items)
+		}
+	}
+}
+
+func setupDB(t *testing.T) (db *sql.DB, closers []func(), e error) {
+	t.Helper()
+
+	defer func() {
+		if e != nil {
+			for _, c := range closers {
+				c()
+			}
+		}
+	}()
+
+	// create a temporary file for e2e testing
+	f, err := os.CreateTemp(".", "*.sqlite3")
+	if err != nil {
+		return nil, nil, err
+	}
+	closers = append(closers, func() {
+		f.Close()
+		os.Remove(f.Name())
+	})
+
+	// set up tables
+	db, err = sql.Open("sqlite3", f.Name())
+	if err != nil {
+		return nil, nil, err
+	}
+	closers = append(closers, func() {
+		db.Close()
+	})
+
+	// create the tables
+	_, err = setupSchema(db)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return db, closers, nil
+}
+
+// readTestImage reads an image file used by tests.
+func readTestImage(t *testing.T, path string) []byte {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read image: %v", err)
+	}
+	return b
+}
+
+// newAddItemRequest builds a multipart request for POST /items.
+// The "image" arg is the path of a file to upload.
+func newAddItemRequest(t *testing.T, args map[string]string) *http.Request {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	for k, v := range args {
+		if k == "image" {
+			fw, err := mw.CreateFormFile(k, filepath.Base(v))
+			if err != nil {
+				t.Fatalf("failed to create form file: %v", err)
+			}
+			if _, err := fw.Write(readTestImage(t, v)); err != nil {
+				t.Fatalf("failed to write form file: %v", err)
+			}
+			continue
+		}
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatalf("failed to write field: %v", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatalf("failed to close multipart writer: %v", err)
+	}
+
+	req := httptest.NewRequest("POST", "/items", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestServeGracefulShutdown(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+			w.Write([]byte("done"))
+		}),
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve(ctx, srv, ln, 5*time.Second)
+	}()
+
+	// start a request, then ask the server to stop while it is in flight
+	type result struct {
+		body string
+		err  error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String())
+		if err != nil {
+			resCh <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		resCh <- result{body: string(b), err: err}
+	}()
+	<-started
+	cancel()
+
+	// new connections are refused once shutdown starts
+	deadline := time.Now().Add(time.Second)
+	for {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("server still accepts connections after shutdown started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	close(release)
+	res := <-resCh
+	if res.err != nil {
+		t.Fatalf("in-flight request failed: %v", res.err)
+	}
+	if res.body != "done" {
+		t.Errorf("expected body %q, got %q", "done", res.body)
+	}
+	if err := <-serveErr; err != nil {
+		t.Errorf("expected graceful shutdown, got %v", err)
+	}
 }
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+)
+
+// ItemStatus is where an item is in its sale.
+type ItemStatus string
+
+const (
+	// StatusOnSale items can be bought.
+	StatusOnSale ItemStatus = "on_sale"
+	// StatusReserved items are held for a buyer until the sale is completed or cancelled.
+	StatusReserved ItemStatus = "reserved"
+	// StatusSold items have been sold. No transition leaves this status.
+	StatusSold ItemStatus = "sold"
+)
+
+// itemStatusTransitions are the statuses each status can change to.
+// Cancelling a reservation puts the item back on sale.
+// A purchase changes an item from on_sale to sold directly, together with its order (see Purchase).
+var itemStatusTransitions = map[ItemStatus][]ItemStatus{
+	StatusOnSale:   {StatusReserved},
+	StatusReserved: {StatusSold, StatusOnSale},
+	StatusSold:     {},
+}
+
+// canTransitionTo reports whether an item can change from status s to to.
+func (s ItemStatus) canTransitionTo(to ItemStatus) bool {
+	return slices.Contains(itemStatusTransitions[s], to)
+}
+
+// parseItemStatus parses and validates an item status.
+func parseItemStatus(s string) (ItemStatus, error) {
+	status := ItemStatus(s)
+	if _, ok := itemStatusTransitions[status]; !ok {
+		return "", fmt.Errorf("unknown status %q: must be one of %s, %s, %s", s, StatusOnSale, StatusReserved, StatusSold)
+	}
+	return status, nil
+}
+
+type UpdateItemStatusRequest struct {
+	ID     int        // path value
+	Status ItemStatus `form:"status"`
+}
+
+// parseUpdateItemStatusRequest parses and validates the request to change the status of an item.
+func parseUpdateItemStatusRequest(r *http.Request) (*UpdateItemStatusRequest, error) {
+	id, err := parsePathID(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// validate the request
+	status := r.FormValue("status")
+	if status == "" {
+		return nil, errors.New("status is required")
+	}
+	req := &UpdateItemStatusRequest{ID: id}
+	req.Status, err = parseItemStatus(status)
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// UpdateItemStatus is a handler to change the status of an item for POST /items/{id}/status .
+// Only the seller can change the status, and only along itemStatusTransitions.
+// It responds 409 Conflict to other transitions, and returns the updated item.
+func (s *Handlers) UpdateItemStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	user := currentUser(w, r)
+	if user == nil {
+		return
+	}
+
+	req, err := parseUpdateItemStatusRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	item, err := s.itemRepo.GetByID(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, errItemNotFound) {
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("item %d not found", req.ID))
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !checkSeller(w, user, item.ID, item.SellerID) {
+		return
+	}
+	if !item.Status.canTransitionTo(req.Status) {
+		writeJSONError(w, http.StatusConflict, fmt.Sprintf("item %d cannot change from %s to %s", item.ID, item.Status, req.Status))
+		return
+	}
+
+	err = s.itemRepo.UpdateStatus(ctx, item.ID, item.Status, req.Status)
+	if err != nil {
+		switch {
+		case errors.Is(err, errItemNotFound):
+			writeJSONError(w, http.StatusNotFound, fmt.Sprintf("item %d not found", req.ID))
+		case errors.Is(err, errStatusConflict):
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("status of item %d was changed by another request", req.ID))
+		default:
+			slog.ErrorContext(ctx, "failed to update item status: ", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	slog.InfoContext(ctx, "item status changed", "id", item.ID, "from", item.Status, "to", req.Status)
+
+	item.Status = req.Status
+	writeJSON(w, r, http.StatusOK, item)
+}
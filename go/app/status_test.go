@@ -0,0 +1,240 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestCanTransitionTo(t *testing.T) {
+	t.Parallel()
+
+	allowed := map[[2]ItemStatus]bool{
+		{StatusOnSale, StatusReserved}: true,
+		{StatusReserved, StatusSold}:   true,
+		{StatusReserved, StatusOnSale}: true,
+	}
+	statuses := []ItemStatus{StatusOnSale, StatusReserved, StatusSold}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[[2]ItemStatus{from, to}]
+			if got := from.canTransitionTo(to); got != want {
+				t.Errorf("%s.canTransitionTo(%s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+	if StatusOnSale.canTransitionTo("shipped") {
+		t.Errorf("expected an unknown status not to be reachable")
+	}
+}
+
+func TestUpdateItemStatus(t *testing.T) {
+	t.Parallel()
+
+	item := func(status ItemStatus) *Item {
+		return &Item{ID: 1, Name: "jacket", Category: "fashion", SellerID: 1, Status: status}
+	}
+	cases := map[string]struct {
+		body      string
+		anonymous bool
+		injector  func(m *MockItemRepository)
+		code      int
+		status    ItemStatus
+	}{
+		"ok: reserve": {
+			body: "status=reserved",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(item(StatusOnSale), nil)
+				m.EXPECT().UpdateStatus(gomock.Any(), 1, StatusOnSale, StatusReserved).Return(nil)
+			},
+			code:   http.StatusOK,
+			status: StatusReserved,
+		},
+		"ok: cancel a reservation": {
+			body: "status=on_sale",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(item(StatusReserved), nil)
+				m.EXPECT().UpdateStatus(gomock.Any(), 1, StatusReserved, StatusOnSale).Return(nil)
+			},
+			code:   http.StatusOK,
+			status: StatusOnSale,
+		},
+		"ok: sell": {
+			body: "status=sold",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(item(StatusReserved), nil)
+				m.EXPECT().UpdateStatus(gomock.Any(), 1, StatusReserved, StatusSold).Return(nil)
+			},
+			code:   http.StatusOK,
+			status: StatusSold,
+		},
+		"ng: sell without a reservation": {
+			body: "status=sold",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(item(StatusOnSale), nil)
+			},
+			code: http.StatusConflict,
+		},
+		"ng: put a sold item back on sale": {
+			body: "status=on_sale",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(item(StatusSold), nil)
+			},
+			code: http.StatusConflict,
+		},
+		"ng: changed by another request": {
+			body: "status=reserved",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(item(StatusOnSale), nil)
+				m.EXPECT().UpdateStatus(gomock.Any(), 1, StatusOnSale, StatusReserved).Return(errStatusConflict)
+			},
+			code: http.StatusConflict,
+		},
+		"ng: unknown status": {
+			body:     "status=shipped",
+			injector: func(m *MockItemRepository) {},
+			code:     http.StatusBadRequest,
+		},
+		"ng: no status": {
+			body:     "",
+			injector: func(m *MockItemRepository) {},
+			code:     http.StatusBadRequest,
+		},
+		"ng: not the seller": {
+			body: "status=reserved",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(&Item{ID: 1, SellerID: 2, Status: StatusOnSale}, nil)
+			},
+			code: http.StatusForbidden,
+		},
+		"ng: not logged in": {
+			body:      "status=reserved",
+			anonymous: true,
+			injector:  func(m *MockItemRepository) {},
+			code:      http.StatusUnauthorized,
+		},
+		"ng: item not found": {
+			body: "status=reserved",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(nil, errItemNotFound)
+			},
+			code: http.StatusNotFound,
+		},
+		"ng: failed to update": {
+			body: "status=reserved",
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().GetByID(gomock.Any(), 1).Return(item(StatusOnSale), nil)
+				m.EXPECT().UpdateStatus(gomock.Any(), 1, StatusOnSale, StatusReserved).Return(errors.New("failed to update"))
+			},
+			code: http.StatusInternalServerError,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			h := &Handlers{itemRepo: mockIR}
+
+			req := httptest.NewRequest("POST", "/items/1/status", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetPathValue("id", "1")
+			if !tt.anonymous {
+				req = withUser(req, &User{ID: 1, Name: "taro"})
+			}
+			rr := httptest.NewRecorder()
+			h.UpdateItemStatus(rr, req)
+
+			if rr.Code != tt.code {
+				t.Errorf("expected status code %d, got %d: %s", tt.code, rr.Code, rr.Body)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			var got Item
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response body: %v", err)
+			}
+			if got.Status != tt.status {
+				t.Errorf("expected status %s, got %s", tt.status, got.Status)
+			}
+		})
+	}
+}
+
+func TestItemStatusE2e(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping e2e test")
+	}
+
+	db, closers, err := setupDB(t)
+	if err != nil {
+		t.Fatalf("failed to set up database: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, c := range closers {
+			c()
+		}
+	})
+
+	repo := &itemRepository{db: db}
+	ctx := context.Background()
+	for _, name := range []string{"jacket", "jeans", "jersey"} {
+		if err := repo.Insert(ctx, &Item{Name: name, Category: "fashion", ImageName: "default.jpg"}); err != nil {
+			t.Fatalf("failed to insert item: %v", err)
+		}
+	}
+
+	// new items are on sale
+	got, err := repo.GetByID(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if got.Status != StatusOnSale {
+		t.Errorf("expected status %s, got %s", StatusOnSale, got.Status)
+	}
+
+	for _, step := range []struct {
+		id       int
+		from, to ItemStatus
+	}{
+		{1, StatusOnSale, StatusReserved},
+		{1, StatusReserved, StatusSold},
+		{2, StatusOnSale, StatusReserved},
+	} {
+		if err := repo.UpdateStatus(ctx, step.id, step.from, step.to); err != nil {
+			t.Fatalf("failed to change item %d from %s to %s: %v", step.id, step.from, step.to, err)
+		}
+	}
+
+	// a transition from a stale status fails
+	if err := repo.UpdateStatus(ctx, 2, StatusOnSale, StatusReserved); !errors.Is(err, errStatusConflict) {
+		t.Errorf("expected errStatusConflict, got %v", err)
+	}
+	if err := repo.UpdateStatus(ctx, 4, StatusOnSale, StatusReserved); !errors.Is(err, errItemNotFound) {
+		t.Errorf("expected errItemNotFound, got %v", err)
+	}
+	// the database rejects unknown statuses
+	if err := repo.UpdateStatus(ctx, 3, StatusOnSale, "shipped"); err == nil {
+		t.Errorf("expected an unknown status to be rejected")
+	}
+
+	for status, want := range map[ItemStatus]string{StatusOnSale: "jersey", StatusReserved: "jeans", StatusSold: "jacket"} {
+		items, _, err := repo.ListItems(ctx, ItemQuery{Status: status, Limit: 10})
+		if err != nil {
+			t.Fatalf("failed to list items: %v", err)
+		}
+		if len(items) != 1 || items[0].Name != want || items[0].Status != status {
+			t.Errorf("expected only %s to be %s, got %+v", want, status, items)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder for image.Decode
+)
+
+// thumbnailWidths are the widths in pixels of the resized variants of each image, in ascending order.
+var thumbnailWidths = []int{150, 400, 1024}
+
+// maxDecodePixels limits the size of images decoded for resizing,
+// so that a small file cannot make the server allocate a huge bitmap.
+const maxDecodePixels = 50_000_000
+
+// thumbnailWidth returns the smallest variant width that is at least the requested width.
+// It returns false if the original image should be served instead.
+func thumbnailWidth(requested int) (int, bool) {
+	for _, w := range thumbnailWidths {
+		if w >= requested {
+			return w, true
+		}
+	}
+	return 0, false
+}
+
+// variantFormat returns the format a variant of an image in format f is encoded in.
+// WebP images are resized to PNG because there is no WebP encoder in the standard library.
+func variantFormat(f imageFormat) imageFormat {
+	if f.ContentType == "image/webp" {
+		pngFormat, _ := imageFormatByExt(".png")
+		return pngFormat
+	}
+	return f
+}
+
+// variantName returns the name of the variant of the image name resized to width.
+// Variants are stored next to the original, e.g. <hash>_w400.jpg for <hash>.jpg.
+func variantName(name string, f imageFormat, width int) string {
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	return fmt.Sprintf("%s_w%d%s", base, width, variantFormat(f).Ext)
+}
+
+// ensureThumbnail returns the name of the variant of the image name resized to width,
+// and generates the variant in store if it does not exist yet.
+// Images that are not wider than width are not resized, and name is returned.
+func ensureThumbnail(ctx context.Context, store ImageStore, name string, width int) (string, error) {
+	format, ok := imageFormatByExt(name)
+	if !ok {
+		return "", fmt.Errorf("unsupported image: %s", name)
+	}
+	thumbName := variantName(name, format, width)
+	exists, err := store.Exists(ctx, thumbName)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return thumbName, nil
+	}
+
+	rc, _, err := store.Get(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return "", fmt.Errorf("failed to read image: %w", err)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image config: %w", err)
+	}
+	if cfg.Width <= width {
+		return name, nil
+	}
+	if cfg.Width*cfg.Height > maxDecodePixels {
+		return "", fmt.Errorf("image is too large to resize: %dx%d", cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	thumbFormat := variantFormat(format)
+	var buf bytes.Buffer
+	if err := encodeImage(&buf, resizeImage(src, width), thumbFormat); err != nil {
+		return "", err
+	}
+	if err := store.Put(ctx, thumbName, &buf, int64(buf.Len()), thumbFormat.ContentType); err != nil {
+		return "", fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+	return thumbName, nil
+}
+
+// resizeImage scales src to width, keeping its aspect ratio.
+func resizeImage(src image.Image, width int) image.Image {
+	b := src.Bounds()
+	height := max(1, b.Dy()*width/b.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	return dst
+}
+
+// encodeImage encodes img in format f.
+func encodeImage(w io.Writer, img image.Image, f imageFormat) error {
+	var err error
+	switch f.ContentType {
+	case "image/jpeg":
+		err = jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case "image/png":
+		err = png.Encode(w, img)
+	case "image/gif":
+		err = gif.Encode(w, img, nil)
+	default:
+		err = fmt.Errorf("cannot encode %s", f.ContentType)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return nil
+}
+
+// generateThumbnails generates every variant of the image name.
+// Failures are only logged, because missing variants are generated again when requested.
+func generateThumbnails(ctx context.Context, store ImageStore, name string) {
+	for _, w := range thumbnailWidths {
+		if _, err := ensureThumbnail(ctx, store, name, w); err != nil {
+			slog.WarnContext(ctx, "failed to generate thumbnail: ", "name", name, "width", w, "error", err)
+			return
+		}
+	}
+}
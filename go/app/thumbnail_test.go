@@ -0,0 +1,185 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+func TestThumbnailWidth(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		requested int
+		want      int
+		ok        bool
+	}{
+		"ok: smaller than the smallest variant": {requested: 100, want: 150, ok: true},
+		"ok: exact variant":                     {requested: 400, want: 400, ok: true},
+		"ok: between variants":                  {requested: 401, want: 1024, ok: true},
+		"ok: wider than every variant":          {requested: 2000, ok: false},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := thumbnailWidth(tt.requested)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("thumbnailWidth(%d) = %d, %v, want %d, %v", tt.requested, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestVariantName(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		name string
+		want string
+	}{
+		"ok: jpeg": {name: "abc.jpg", want: "abc_w400.jpg"},
+		"ok: png":  {name: "abc.png", want: "abc_w400.png"},
+		"ok: webp": {name: "abc.webp", want: "abc_w400.png"},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			f, _ := imageFormatByExt(tt.name)
+			if got := variantName(tt.name, f, 400); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStoreImageThumbnails(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryImageStore()
+	h := &Handlers{imgStore: store}
+
+	ctx := context.Background()
+	filePath := storeTestImage(t, h, newSizedTestImage(t, "image/png", 800, 600))
+	name := path.Base(filePath)
+
+	// variants narrower than the original are generated on upload
+	f, _ := imageFormatByExt(name)
+	for _, w := range []int{150, 400} {
+		checkImageSize(t, store, variantName(name, f, w), w, w*600/800)
+	}
+	if ok, err := store.Exists(ctx, variantName(name, f, 1024)); err != nil || ok {
+		t.Errorf("expected no variant wider than the original, got %v, %v", ok, err)
+	}
+
+	cases := map[string]struct {
+		query    string
+		code     int
+		wantSize image.Point
+	}{
+		"ok: original":                 {query: "", code: http.StatusOK, wantSize: image.Pt(800, 600)},
+		"ok: closest variant":          {query: "?w=300", code: http.StatusOK, wantSize: image.Pt(400, 300)},
+		"ok: wider than the original":  {query: "?w=1000", code: http.StatusOK, wantSize: image.Pt(800, 600)},
+		"ok: wider than every variant": {query: "?w=4000", code: http.StatusOK, wantSize: image.Pt(800, 600)},
+		"ng: not a number":             {query: "?w=large", code: http.StatusBadRequest},
+		"ng: zero":                     {query: "?w=0", code: http.StatusBadRequest},
+	}
+
+	for name2, tt := range cases {
+		t.Run(name2, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /images/{filename}", h.GetImage)
+			res := httptest.NewRecorder()
+			mux.ServeHTTP(res, httptest.NewRequest("GET", "/images/"+name+tt.query, nil))
+
+			if res.Code != tt.code {
+				t.Fatalf("expected status code %d, got %d", tt.code, res.Code)
+			}
+			if tt.code != http.StatusOK {
+				return
+			}
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(res.Body.Bytes()))
+			if err != nil {
+				t.Fatalf("failed to decode served image: %v", err)
+			}
+			if got := image.Pt(cfg.Width, cfg.Height); got != tt.wantSize {
+				t.Errorf("expected size %v, got %v", tt.wantSize, got)
+			}
+		})
+	}
+}
+
+func TestEnsureThumbnailLazily(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	store := NewLocalImageStore(dir)
+	if err := os.WriteFile(filepath.Join(dir, "abc.jpg"), newSizedTestImage(t, "image/jpeg", 2000, 1000), 0o644); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+
+	// a missing variant is generated and cached
+	ctx := context.Background()
+	got, err := ensureThumbnail(ctx, store, "abc.jpg", 1024)
+	if err != nil {
+		t.Fatalf("failed to get thumbnail: %v", err)
+	}
+	if want := "abc_w1024.jpg"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+	checkImageSize(t, store, got, 1024, 512)
+	info, err := store.Stat(ctx, got)
+	if err != nil {
+		t.Fatalf("failed to stat thumbnail: %v", err)
+	}
+
+	// the cached variant is reused
+	if _, err := ensureThumbnail(ctx, store, "abc.jpg", 1024); err != nil {
+		t.Fatalf("failed to get thumbnail: %v", err)
+	}
+	info2, err := store.Stat(ctx, got)
+	if err != nil {
+		t.Fatalf("failed to stat thumbnail: %v", err)
+	}
+	if !info2.ModTime.Equal(info.ModTime) {
+		t.Errorf("expected the cached thumbnail to be reused")
+	}
+
+	// an image narrower than the requested width is served as is
+	got, err = ensureThumbnail(ctx, store, got, 1500)
+	if err != nil {
+		t.Fatalf("failed to get thumbnail: %v", err)
+	}
+	if want := "abc_w1024.jpg"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+// checkImageSize checks the size of the named image in store.
+func checkImageSize(t *testing.T, store ImageStore, name string, width, height int) {
+	t.Helper()
+
+	rc, _, err := store.Get(context.Background(), name)
+	if err != nil {
+		t.Fatalf("failed to open image %s: %v", name, err)
+	}
+	defer rc.Close()
+	cfg, _, err := image.DecodeConfig(rc)
+	if err != nil {
+		t.Fatalf("failed to decode image: %v", err)
+	}
+	if cfg.Width != width || cfg.Height != height {
+		t.Errorf("expected %s to be %dx%d, got %dx%d", name, width, height, cfg.Width, cfg.Height)
+	}
+}
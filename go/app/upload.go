@@ -0,0 +1,146 @@
+package app
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// maxFormValueBytes limits the size of a non-file form field.
+const maxFormValueBytes = 64 << 10
+
+// imageUpload is an uploaded image saved to a temporary file, waiting to be stored.
+type imageUpload struct {
+	// TempPath is the temporary file holding the image. It is empty once the image is stored or discarded.
+	TempPath string
+	// Hash is the hex-encoded SHA-256 of the image.
+	Hash   string
+	Size   int64
+	Format imageFormat
+}
+
+// FileName returns the content-addressed file name of the image.
+func (u *imageUpload) FileName() string {
+	return u.Hash + u.Format.Ext
+}
+
+// discard removes the temporary file if the image has not been stored.
+func (u *imageUpload) discard() {
+	if u == nil || u.TempPath == "" {
+		return
+	}
+	os.Remove(u.TempPath)
+	u.TempPath = ""
+}
+
+// saveUpload streams an image from r to a temporary file in dir, computing its SHA-256 on the way.
+// The format is detected from the first bytes, so unsupported files are rejected before they are written.
+// Errors from r, such as *http.MaxBytesError, are wrapped.
+func saveUpload(dir string, r io.Reader) (*imageUpload, error) {
+	br := bufio.NewReaderSize(r, 512)
+	head, err := br.Peek(512)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	if len(head) == 0 {
+		return nil, errors.New("image is empty")
+	}
+	format, err := detectImageFormat(head)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), br)
+	if err == nil {
+		err = tmp.Close()
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to save image: %w", err)
+	}
+
+	return &imageUpload{
+		TempPath: tmp.Name(),
+		Hash:     hex.EncodeToString(hash.Sum(nil)),
+		Size:     size,
+		Format:   format,
+	}, nil
+}
+
+// parseItemForm reads the fields of a form without buffering the "image" file in memory.
+// The image is streamed to a temporary file in dir, and is nil if no file is uploaded.
+// URL-encoded forms are accepted too, without an image.
+// The caller must discard the returned image when it is not stored.
+func parseItemForm(r *http.Request, dir string) (fields url.Values, image *imageUpload, err error) {
+	mr, err := r.MultipartReader()
+	if errors.Is(err, http.ErrNotMultipart) {
+		if err := r.ParseForm(); err != nil {
+			return nil, nil, fmt.Errorf("invalid form body: %w", err)
+		}
+		return r.PostForm, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid form body: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			image.discard()
+		}
+	}()
+	fields = url.Values{}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return fields, image, nil
+		}
+		if err != nil {
+			return nil, image, fmt.Errorf("invalid form body: %w", err)
+		}
+
+		name := part.FormName()
+		if name == "image" && part.FileName() != "" {
+			if image != nil {
+				return nil, image, errors.New("only one image can be uploaded")
+			}
+			image, err = saveUpload(dir, part)
+			if err != nil {
+				return nil, image, err
+			}
+			continue
+		}
+		if name == "" {
+			continue
+		}
+		value, err := io.ReadAll(io.LimitReader(part, maxFormValueBytes+1))
+		if err != nil {
+			return nil, image, fmt.Errorf("invalid form body: %w", err)
+		}
+		if len(value) > maxFormValueBytes {
+			return nil, image, fmt.Errorf("form field %s is too large", name)
+		}
+		fields.Add(name, string(value))
+	}
+}
+
+// requestErrorStatus returns the status code for an error parsing a request:
+// 413 if the body exceeds the upload limit, and 400 otherwise.
+func requestErrorStatus(err error) int {
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
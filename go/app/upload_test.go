@@ -0,0 +1,199 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/mock/gomock"
+)
+
+func TestSaveUpload(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]struct {
+		data []byte
+		err  bool
+	}{
+		"ok: image":       {data: newSizedTestImage(t, "image/png", 64, 64)},
+		"ng: empty":       {data: []byte{}, err: true},
+		"ng: unsupported": {data: []byte("#!/bin/sh\necho not an image\n"), err: true},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			got, err := saveUpload(dir, bytes.NewReader(tt.data))
+			if err != nil {
+				if !tt.err {
+					t.Errorf("unexpected error: %v", err)
+				}
+				checkNoFiles(t, dir)
+				return
+			}
+			if tt.err {
+				t.Fatal("expected an error")
+			}
+
+			sum := sha256.Sum256(tt.data)
+			if got.Hash != hex.EncodeToString(sum[:]) {
+				t.Errorf("unexpected hash %s", got.Hash)
+			}
+			if got.Size != int64(len(tt.data)) {
+				t.Errorf("expected size %d, got %d", len(tt.data), got.Size)
+			}
+			saved, err := os.ReadFile(got.TempPath)
+			if err != nil {
+				t.Fatalf("failed to read temporary file: %v", err)
+			}
+			if !bytes.Equal(saved, tt.data) {
+				t.Error("temporary file differs from the upload")
+			}
+
+			got.discard()
+			checkNoFiles(t, dir)
+		})
+	}
+}
+
+func TestStoreUpload(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	h := &Handlers{imgStore: NewLocalImageStore(dir), tmpDir: t.TempDir()}
+	data := newTestImage(t, "image/png")
+
+	// the second upload of the same image is deduplicated and its temporary file is removed
+	var paths []string
+	for range 2 {
+		upload, err := saveUpload(h.tmpDir, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("failed to save upload: %v", err)
+		}
+		path, err := h.storeUpload(context.Background(), upload, false)
+		if err != nil {
+			t.Fatalf("failed to store upload: %v", err)
+		}
+		if upload.TempPath != "" {
+			t.Errorf("expected the temporary file to be consumed")
+		}
+		paths = append(paths, path)
+	}
+	if paths[0] != paths[1] {
+		t.Errorf("expected the same path, got %s and %s", paths[0], paths[1])
+	}
+	checkNoFiles(t, h.tmpDir)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(paths[0]) {
+		t.Errorf("expected only %s in the image dir, got %v", filepath.Base(paths[0]), entries)
+	}
+	info, err := os.Stat(filepath.Join(dir, filepath.Base(paths[0])))
+	if err != nil {
+		t.Fatalf("failed to stat image: %v", err)
+	}
+	if info.Mode().Perm() != 0644 {
+		t.Errorf("expected mode 0644, got %v", info.Mode().Perm())
+	}
+}
+
+func TestAddItemUploadLimit(t *testing.T) {
+	t.Parallel()
+
+	image := newSizedTestImage(t, "image/png", 256, 256)
+	cases := map[string]struct {
+		maxUploadBytes int64
+		injector       func(m *MockItemRepository)
+		code           int
+	}{
+		"ok: within the limit": {
+			maxUploadBytes: int64(len(image)) + 1024,
+			injector: func(m *MockItemRepository) {
+				m.EXPECT().SaveImage(gomock.Any(), gomock.Any()).Return(true, nil)
+				m.EXPECT().Insert(gomock.Any(), gomock.Any()).Return(nil)
+			},
+			code: http.StatusOK,
+		},
+		"ng: too large": {
+			maxUploadBytes: int64(len(image)) / 2,
+			injector:       func(m *MockItemRepository) {},
+			code:           http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for name, tt := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockIR := NewMockItemRepository(ctrl)
+			tt.injector(mockIR)
+			dir := t.TempDir()
+			h := &Handlers{imgStore: NewMemoryImageStore(), tmpDir: dir, itemRepo: mockIR, maxUploadBytes: tt.maxUploadBytes}
+
+			body := &bytes.Buffer{}
+			mw := multipart.NewWriter(body)
+			mw.WriteField("name", "jacket")
+			mw.WriteField("category", "fashion")
+			mw.WriteField("price", "1500")
+			fw, err := mw.CreateFormFile("image", "jacket.png")
+			if err != nil {
+				t.Fatalf("failed to create form file: %v", err)
+			}
+			fw.Write(image)
+			mw.Close()
+			req := httptest.NewRequest("POST", "/items", body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			req = withUser(req, &User{ID: 1, Name: "taro"})
+
+			rr := httptest.NewRecorder()
+			h.AddItem(rr, req)
+
+			if rr.Code != tt.code {
+				t.Errorf("expected status code %d, got %d: %s", tt.code, rr.Code, rr.Body.String())
+			}
+			// the uploaded image is removed from the temporary directory
+			checkNoFiles(t, dir)
+		})
+	}
+}
+
+// storeTestImage stores image as an upload, as AddItem does, and returns its path.
+func storeTestImage(t *testing.T, h *Handlers, image []byte) string {
+	t.Helper()
+
+	upload, err := saveUpload(h.tmpDir, bytes.NewReader(image))
+	if err != nil {
+		t.Fatalf("failed to save upload: %v", err)
+	}
+	filePath, err := h.storeUpload(context.Background(), upload, false)
+	if err != nil {
+		t.Fatalf("failed to store image: %v", err)
+	}
+	return filePath
+}
+
+// checkNoFiles checks that dir is empty.
+func checkNoFiles(t *testing.T, dir string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no files in %s, got %v", dir, entries)
+	}
+}
@@ -1,20 +1,37 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
 	"mercari-build-training/app"
 	"os"
 )
 
-const (
-	port         = "9001"
-	imageDirPath = "images"
-)
-
 func main() {
 	// This is the entry point of the application.
-	// You don't need to modify this function.
-	os.Exit(app.Server{
-		Port:         port,
-		ImageDirPath: imageDirPath,
-	}.Run())
+	// The server is configured by flags, MERCARI_* environment variables and a config file.
+	server, args, err := app.LoadServer(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// `migrate [status|up]` manages the database schema instead of starting the server.
+	if len(args) > 0 && args[0] == "migrate" {
+		os.Exit(app.RunMigrate(server.DBPath, args[1:]))
+	}
+	// `gc` deletes the images no item has used for the grace period once.
+	if len(args) > 0 && args[0] == "gc" {
+		os.Exit(server.RunGC(args[1:]))
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", args[0])
+		os.Exit(2)
+	}
+
+	os.Exit(server.Run())
 }
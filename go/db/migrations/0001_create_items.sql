@@ -11,4 +11,4 @@ CREATE TABLE IF NOT EXISTS items (
     category_id INTEGER NOT NULL,
     image_name TEXT NOT NULL,
     FOREIGN KEY (category_id) REFERENCES categories(id)
-);
\ No newline at end of file
+);
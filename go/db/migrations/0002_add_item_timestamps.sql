@@ -0,0 +1,21 @@
+-- add created_at and deleted_at to items
+-- SQLite cannot add a column with a non-constant default, so the table is rebuilt
+CREATE TABLE items_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    category_id INTEGER NOT NULL,
+    image_name TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- deleted_at is set when the item is soft-deleted
+    deleted_at DATETIME,
+    FOREIGN KEY (category_id) REFERENCES categories(id)
+);
+
+INSERT INTO items_new (id, name, category_id, image_name)
+SELECT id, name, category_id, image_name FROM items;
+
+DROP TABLE items;
+ALTER TABLE items_new RENAME TO items;
+
+-- index for listing items newest first
+CREATE INDEX items_created_at ON items (created_at, id);
@@ -0,0 +1,42 @@
+-- images table: one row per stored image file, shared by every item that uses it
+CREATE TABLE images (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    -- name is the file name in the image store, <sha256>.<ext>
+    name TEXT UNIQUE NOT NULL,
+    hash TEXT NOT NULL,
+    size INTEGER NOT NULL,
+    content_type TEXT NOT NULL,
+    -- ref_count is the number of items, including soft-deleted ones, using the image
+    ref_count INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    -- unreferenced_at is when ref_count last dropped to 0, or NULL while the image is used
+    unreferenced_at DATETIME
+);
+
+-- index for finding images to garbage collect
+CREATE INDEX images_unreferenced_at ON images (unreferenced_at) WHERE ref_count = 0;
+
+-- link items to their image
+-- items created before this migration are not linked, so their files are never collected
+ALTER TABLE items ADD COLUMN image_id INTEGER REFERENCES images(id);
+
+-- keep ref_count in sync with items
+CREATE TRIGGER images_ref_after_item_insert AFTER INSERT ON items
+WHEN new.image_id IS NOT NULL BEGIN
+    UPDATE images SET ref_count = ref_count + 1, unreferenced_at = NULL WHERE id = new.image_id;
+END;
+
+CREATE TRIGGER images_ref_after_item_update AFTER UPDATE OF image_id ON items
+WHEN old.image_id IS NOT new.image_id BEGIN
+    UPDATE images SET ref_count = ref_count + 1, unreferenced_at = NULL WHERE id = new.image_id;
+    UPDATE images SET ref_count = ref_count - 1,
+        unreferenced_at = CASE WHEN ref_count = 1 THEN CURRENT_TIMESTAMP END
+    WHERE id = old.image_id;
+END;
+
+CREATE TRIGGER images_ref_after_item_delete AFTER DELETE ON items
+WHEN old.image_id IS NOT NULL BEGIN
+    UPDATE images SET ref_count = ref_count - 1,
+        unreferenced_at = CASE WHEN ref_count = 1 THEN CURRENT_TIMESTAMP END
+    WHERE id = old.image_id;
+END;
@@ -0,0 +1,22 @@
+-- users table
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    -- name is unique ignoring case
+    name TEXT UNIQUE NOT NULL COLLATE NOCASE,
+    -- password_hash is the bcrypt hash of the password
+    password_hash TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- sessions table: one row per logged-in client
+CREATE TABLE sessions (
+    -- token_hash is the SHA-256 of the session token, so that a leaked database cannot be used to log in
+    token_hash TEXT PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+-- index for deleting expired sessions
+CREATE INDEX sessions_expires_at ON sessions (expires_at);
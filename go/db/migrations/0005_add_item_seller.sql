@@ -0,0 +1,6 @@
+-- add the seller of each item
+-- items listed before users existed have no seller, so seller_id is NULL for them
+ALTER TABLE items ADD COLUMN seller_id INTEGER REFERENCES users(id);
+
+-- index for listing the items of a seller
+CREATE INDEX items_seller_id ON items (seller_id, id);
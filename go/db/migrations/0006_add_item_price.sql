@@ -0,0 +1,8 @@
+-- add the price of each item
+-- price is in the minor units of currency, e.g. cents for USD and yen for JPY,
+-- so that it is never rounded
+ALTER TABLE items ADD COLUMN price INTEGER NOT NULL DEFAULT 0 CHECK (price >= 0);
+ALTER TABLE items ADD COLUMN currency TEXT NOT NULL DEFAULT 'JPY';
+
+-- index for filtering items by price
+CREATE INDEX items_currency_price ON items (currency, price, id);
@@ -0,0 +1,6 @@
+-- add the sale status of each item
+-- items move from on_sale to reserved to sold, and reservations can be cancelled back to on_sale
+ALTER TABLE items ADD COLUMN status TEXT NOT NULL DEFAULT 'on_sale' CHECK (status IN ('on_sale', 'reserved', 'sold'));
+
+-- index for filtering items by status
+CREATE INDEX items_status ON items (status, id);
@@ -0,0 +1,19 @@
+-- orders table: one row per purchased item
+CREATE TABLE orders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    -- item_id is unique, so that an item is never sold twice
+    item_id INTEGER UNIQUE NOT NULL,
+    buyer_id INTEGER NOT NULL,
+    seller_id INTEGER NOT NULL,
+    -- price and currency are those of the item when it was bought
+    price INTEGER NOT NULL,
+    currency TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (item_id) REFERENCES items(id),
+    FOREIGN KEY (buyer_id) REFERENCES users(id),
+    FOREIGN KEY (seller_id) REFERENCES users(id)
+);
+
+-- indexes for listing the orders of a buyer and of a seller
+CREATE INDEX orders_buyer_id ON orders (buyer_id, id);
+CREATE INDEX orders_seller_id ON orders (seller_id, id);
@@ -0,0 +1,19 @@
+// Package migrations embeds the SQL files that define the database schema.
+//
+// Migrations are named NNNN_description.sql and applied in version order.
+// Once a migration is released, add a new one instead of editing it.
+package migrations
+
+import "embed"
+
+// FS contains the numbered schema migrations.
+//
+//go:embed [0-9]*.sql
+var FS embed.FS
+
+// SearchIndex creates the FTS5 search index over items and the triggers keeping it in sync.
+// It is not a numbered migration because it can only be applied when SQLite is built with FTS5,
+// and it is idempotent so that it can be applied on every start.
+//
+//go:embed items_fts.sql
+var SearchIndex string
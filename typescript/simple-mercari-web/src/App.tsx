@@ -1,11 +1,17 @@
 import { useState } from 'react';
 import './App.css';
+import { User, getSession } from '~/api';
 import { ItemList } from '~/components/ItemList';
 import { Listing } from '~/components/Listing';
+import { Login } from '~/components/Login';
 
 function App() {
   // reload ItemList after Listing complete
   const [reload, setReload] = useState(true);
+  // only logged-in users can list items
+  const [user, setUser] = useState<User | null>(
+    () => getSession()?.user ?? null
+  );
   return (
     <div>
       <header className="Title">
@@ -14,8 +20,19 @@ function App() {
         </p>
       </header>
       <div>
-        <Listing onListingCompleted={() => setReload(true)} />
+        <Login user={user} onUserChange={setUser} />
       </div>
+      {user && (
+        <div>
+          <Listing
+            onListingCompleted={() => {
+              setReload(true);
+              // the session is cleared if the server rejected it
+              setUser(getSession()?.user ?? null);
+            }}
+          />
+        </div>
+      )}
       <div>
         <ItemList reload={reload} onLoadCompleted={() => setReload(false)} />
       </div>
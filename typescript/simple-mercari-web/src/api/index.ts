@@ -1,18 +1,30 @@
 const SERVER_URL = import.meta.env.VITE_BACKEND_URL || 'http://127.0.0.1:9001';
 
+// ItemStatus is where an item is in its sale: on_sale → reserved → sold.
+export type ItemStatus = 'on_sale' | 'reserved' | 'sold';
+
 export interface Item {
   id: number;
   name: string;
   category: string;
   image_name: string;
+  // price is an integer in the minor units of currency, e.g. cents for USD and yen for JPY.
+  price: number;
+  currency: string;
+  status: ItemStatus;
+  created_at: string;
 }
 
 export interface ItemListResponse {
   items: Item[];
+  next_cursor?: string;
 }
 
-export const fetchItems = async (): Promise<ItemListResponse> => {
-  const response = await fetch(`${SERVER_URL}/items`, {
+export const fetchItems = async (
+  cursor?: string
+): Promise<ItemListResponse> => {
+  const query = cursor ? `?cursor=${encodeURIComponent(cursor)}` : '';
+  const response = await fetch(`${SERVER_URL}/items${query}`, {
     method: 'GET',
     mode: 'cors',
     headers: {
@@ -27,23 +39,126 @@ export const fetchItems = async (): Promise<ItemListResponse> => {
   return response.json();
 };
 
+// formatPrice formats a price in minor units, e.g. 1999 USD as $19.99.
+export const formatPrice = (price: number, currency: string): string => {
+  const format = new Intl.NumberFormat(undefined, {
+    style: 'currency',
+    currency,
+  });
+  const digits = format.resolvedOptions().maximumFractionDigits ?? 0;
+  return format.format(price / 10 ** digits);
+};
+
 export interface CreateItemInput {
   name: string;
   category: string;
   image: string | File;
+  // price is an integer in the minor units of the currency, JPY unless specified.
+  price: number;
+  currency?: string;
 }
 
+export interface User {
+  id: number;
+  name: string;
+  created_at: string;
+}
+
+export interface Session {
+  user: User;
+  token: string;
+  expires_at: string;
+}
+
+// SESSION_KEY is the localStorage key of the logged-in session.
+const SESSION_KEY = 'session';
+
+// getSession returns the logged-in session, or null if missing or expired.
+export const getSession = (): Session | null => {
+  const stored = localStorage.getItem(SESSION_KEY);
+  if (!stored) {
+    return null;
+  }
+  const session: Session = JSON.parse(stored);
+  if (new Date(session.expires_at) <= new Date()) {
+    localStorage.removeItem(SESSION_KEY);
+    return null;
+  }
+  return session;
+};
+
+// authHeaders returns the Authorization header of the session, if any.
+const authHeaders = (): Record<string, string> => {
+  const session = getSession();
+  return session ? { Authorization: `Bearer ${session.token}` } : {};
+};
+
+// readError returns the message of an error response.
+const readError = async (response: Response): Promise<string> => {
+  const text = await response.text();
+  try {
+    return JSON.parse(text).message ?? text;
+  } catch {
+    return text.trim();
+  }
+};
+
+const authenticate = async (
+  path: 'signup' | 'login',
+  name: string,
+  password: string
+): Promise<Session> => {
+  const response = await fetch(`${SERVER_URL}/auth/${path}`, {
+    method: 'POST',
+    mode: 'cors',
+    body: new URLSearchParams({ name, password }),
+  });
+
+  if (response.status >= 400) {
+    throw new Error(await readError(response));
+  }
+  const session: Session = await response.json();
+  localStorage.setItem(SESSION_KEY, JSON.stringify(session));
+  return session;
+};
+
+export const signup = (name: string, password: string): Promise<Session> =>
+  authenticate('signup', name, password);
+
+export const login = (name: string, password: string): Promise<Session> =>
+  authenticate('login', name, password);
+
+export const logout = async (): Promise<void> => {
+  const headers = authHeaders();
+  localStorage.removeItem(SESSION_KEY);
+  await fetch(`${SERVER_URL}/auth/logout`, {
+    method: 'POST',
+    mode: 'cors',
+    headers,
+  });
+};
+
 export const postItem = async (input: CreateItemInput): Promise<Response> => {
   const data = new FormData();
   data.append('name', input.name);
   data.append('category', input.category);
   data.append('image', input.image);
+  data.append('price', String(input.price));
+  if (input.currency) {
+    data.append('currency', input.currency);
+  }
   const response = await fetch(`${SERVER_URL}/items`, {
     method: 'POST',
     mode: 'cors',
+    headers: authHeaders(),
     body: data,
   });
 
+  if (response.status === 401) {
+    // the session expired or was logged out elsewhere
+    localStorage.removeItem(SESSION_KEY);
+    throw new Error('Please log in again to list items');
+  }
   if (response.status >= 400) {
     throw new Error('Failed to post item to the server');
   }
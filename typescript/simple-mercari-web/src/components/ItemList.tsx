@@ -1,5 +1,5 @@
 import { useEffect, useState } from 'react';
-import { Item, fetchItems } from '~/api';
+import { Item, fetchItems, formatPrice } from '~/api';
 
 const SERVER_URL = import.meta.env.VITE_BACKEND_URL || 'http://127.0.0.1:9001';
 
@@ -10,12 +10,15 @@ interface Prop {
 
 export const ItemList = ({ reload, onLoadCompleted }: Prop) => {
   const [items, setItems] = useState<Item[]>([]);
+  // nextCursor is the cursor of the next page, or undefined on the last page.
+  const [nextCursor, setNextCursor] = useState<string>();
   useEffect(() => {
     const fetchData = () => {
       fetchItems()
         .then((data) => {
           console.debug('GET success:', data);
-          setItems(data.items);
+          setItems(data.items ?? []);
+          setNextCursor(data.next_cursor);
           onLoadCompleted();
         })
         .catch((error) => {
@@ -28,26 +31,57 @@ export const ItemList = ({ reload, onLoadCompleted }: Prop) => {
     }
   }, [reload, onLoadCompleted]);
 
+  // loadMore appends the next page to the items
+  const loadMore = () => {
+    fetchItems(nextCursor)
+      .then((data) => {
+        console.debug('GET success:', data);
+        setItems((prev) => [...prev, ...(data.items ?? [])]);
+        setNextCursor(data.next_cursor);
+      })
+      .catch((error) => {
+        console.error('GET error:', error);
+      });
+  };
+
   return (
-    <div className='ItemField'>
-      {items?.map((item) => {
-        return (
-          <div key={item.id} className="ItemList">
-            {/* Show item images */}
-            <img
-              src={`${SERVER_URL}/${item.image_name}`}
-              alt={item.name}
-            />
-            <p>
-              <span>ID: {item.id}</span>
-              <br />
-              <span>Name: {item.name}</span>
-              <br />
-              <span>Category: {item.category}</span>
-            </p>
-          </div>
-        );
-      })}
+    <div>
+      <div className='ItemField'>
+        {items?.map((item) => {
+          return (
+            <div
+              key={item.id}
+              className={item.status === 'sold' ? 'ItemList sold' : 'ItemList'}
+            >
+              {/* Show item images */}
+              <img
+                src={`${SERVER_URL}/${item.image_name}?w=400`}
+                alt={item.name}
+              />
+              <p>
+                <span>ID: {item.id}</span>
+                <br />
+                <span>Name: {item.name}</span>
+                <br />
+                <span>Category: {item.category}</span>
+                <br />
+                <span>Price: {formatPrice(item.price, item.currency)}</span>
+                {item.status !== 'on_sale' && (
+                  <>
+                    <br />
+                    <span>{item.status === 'sold' ? 'SOLD' : 'Reserved'}</span>
+                  </>
+                )}
+              </p>
+            </div>
+          );
+        })}
+      </div>
+      {nextCursor && (
+        <button className='LoadMore' onClick={loadMore}>
+          Load more
+        </button>
+      )}
     </div>
   );
 };
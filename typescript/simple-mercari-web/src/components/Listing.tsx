@@ -9,6 +9,7 @@ type FormDataType = {
   name: string;
   category: string;
   image: string | File;
+  price: string;
 };
 
 export const Listing = ({ onListingCompleted }: Prop) => {
@@ -16,6 +17,7 @@ export const Listing = ({ onListingCompleted }: Prop) => {
     name: '',
     category: '',
     image: '',
+    price: '',
   };
   const [values, setValues] = useState<FormDataType>(initialState);
 
@@ -37,7 +39,7 @@ export const Listing = ({ onListingCompleted }: Prop) => {
     event.preventDefault();
 
     // Validate field before submit
-    const REQUIRED_FILEDS = ['name', 'image'];
+    const REQUIRED_FILEDS = ['name', 'image', 'price'];
     const missingFields = Object.entries(values)
       .filter(([, value]) => !value && REQUIRED_FILEDS.includes(value))
       .map(([key]) => key);
@@ -52,13 +54,14 @@ export const Listing = ({ onListingCompleted }: Prop) => {
       name: values.name,
       category: values.category,
       image: values.image,
+      price: Number(values.price),
     })
       .then(() => {
         alert('Item listed successfully');
       })
       .catch((error) => {
         console.error('POST error:', error);
-        alert('Failed to list this item');
+        alert(`Failed to list this item: ${error.message}`);
       })
       .finally(() => {
         onListingCompleted();
@@ -89,10 +92,22 @@ export const Listing = ({ onListingCompleted }: Prop) => {
             onChange={onValueChange}
             value={values.category}
           />
+          <input
+            type="number"
+            name="price"
+            id="price"
+            placeholder="price (JPY)"
+            min={0}
+            step={1}
+            onChange={onValueChange}
+            required
+            value={values.price}
+          />
           <input
             type="file"
             name="image"
             id="image"
+            accept="image/jpeg,image/png,image/webp,image/gif"
             onChange={onFileChange}
             required
             ref={uploadImageRef}
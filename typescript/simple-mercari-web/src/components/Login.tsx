@@ -0,0 +1,97 @@
+import { useState } from 'react';
+import { User, login, logout, signup } from '~/api';
+
+interface Prop {
+  user: User | null;
+  onUserChange: (user: User | null) => void;
+}
+
+type FormDataType = {
+  name: string;
+  password: string;
+};
+
+export const Login = ({ user, onUserChange }: Prop) => {
+  const initialState = {
+    name: '',
+    password: '',
+  };
+  const [values, setValues] = useState<FormDataType>(initialState);
+
+  const onValueChange = (event: React.ChangeEvent<HTMLInputElement>) => {
+    setValues({
+      ...values,
+      [event.target.name]: event.target.value,
+    });
+  };
+  const onSubmit = (event: React.FormEvent<HTMLFormElement>) => {
+    event.preventDefault();
+
+    // the button that submitted the form chooses between login and signup
+    const submitter = (event.nativeEvent as SubmitEvent).submitter;
+    const authenticate = submitter?.id === 'signup' ? signup : login;
+    authenticate(values.name, values.password)
+      .then((session) => {
+        onUserChange(session.user);
+        setValues(initialState);
+      })
+      .catch((error) => {
+        console.error('POST error:', error);
+        alert(`Failed to log in: ${error.message}`);
+      });
+  };
+  const onLogout = () => {
+    logout()
+      .catch((error) => {
+        console.error('POST error:', error);
+      })
+      .finally(() => {
+        onUserChange(null);
+      });
+  };
+
+  if (user) {
+    return (
+      <div className="Login">
+        <span>Logged in as {user.name}</span>
+        <button type="button" onClick={onLogout}>
+          Log out
+        </button>
+      </div>
+    );
+  }
+  return (
+    <div className="Login">
+      <form onSubmit={onSubmit}>
+        <div>
+          <input
+            type="text"
+            name="name"
+            id="login-name"
+            placeholder="name"
+            autoComplete="username"
+            onChange={onValueChange}
+            required
+            value={values.name}
+          />
+          <input
+            type="password"
+            name="password"
+            id="login-password"
+            placeholder="password"
+            autoComplete="current-password"
+            onChange={onValueChange}
+            required
+            value={values.password}
+          />
+          <button type="submit" id="login">
+            Log in
+          </button>
+          <button type="submit" id="signup">
+            Sign up
+          </button>
+        </div>
+      </form>
+    </div>
+  );
+};